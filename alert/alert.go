@@ -0,0 +1,110 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+
+	"github.com/steady-bytes/draft/pkg/chassis"
+)
+
+type (
+	// Notifier is the pipeline every alert raised by the application is sent through. Each
+	// alert is logged, kept in a short history and, if a webhook is configured, POSTed to it
+	// as JSON.
+	Notifier interface {
+		// Notify delivers the alert.
+		Notify(ctx context.Context, alert models.Alert) error
+		// Recent returns the latest alerts, newest first.
+		Recent(limit int) []models.Alert
+	}
+	notifier struct {
+		logger  chassis.Logger
+		webhook string
+		client  *http.Client
+
+		mu     sync.RWMutex
+		recent []models.Alert
+	}
+	config struct {
+		Webhook string
+	}
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	maxRecent      = 100
+)
+
+func NewNotifier(logger chassis.Logger) Notifier {
+	c := config{}
+	err := chassis.GetConfig().UnmarshalKey("alerts", &c)
+	if err != nil {
+		logger.WithError(err).Error("failed to read alerts config")
+		os.Exit(1)
+	}
+
+	return &notifier{
+		logger:  logger.WithField("worker", "alerts"),
+		webhook: c.Webhook,
+		client:  &http.Client{Timeout: webhookTimeout},
+
+		mu:     sync.RWMutex{},
+		recent: []models.Alert{},
+	}
+}
+
+func (n *notifier) Notify(ctx context.Context, alert models.Alert) error {
+	if alert.Time.IsZero() {
+		alert.Time = time.Now()
+	}
+	n.logger.WithField("type", alert.Type).WithField("target", alert.Target).Info(alert.Title)
+
+	n.mu.Lock()
+	n.recent = append(n.recent, alert)
+	if len(n.recent) > maxRecent {
+		n.recent = n.recent[len(n.recent)-maxRecent:]
+	}
+	n.mu.Unlock()
+
+	if n.webhook == "" {
+		return nil
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+func (n *notifier) Recent(limit int) []models.Alert {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	recent := slices.Clone(n.recent)
+	slices.Reverse(recent)
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent
+}
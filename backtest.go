@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+// backtest asks a running instance of the service to evaluate its score predictions against
+// the history it has collected and prints the results.
+func backtest(args []string) {
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address of the running service")
+	sub := flags.String("sub", "", "subreddit to backtest (required)")
+	flags.Parse(args)
+	if *sub == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/predictions/backtest?sub=%s", *addr, url.QueryEscape(*sub)))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to call service:", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "backtest failed: %s: %s", resp.Status, body)
+		os.Exit(1)
+	}
+
+	result := models.Backtest{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to decode response:", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "subreddit\t%s\n", result.Subreddit)
+	fmt.Fprintf(w, "samples\t%d\n", result.Samples)
+	fmt.Fprintf(w, "folds\t%d\n", result.Folds)
+	fmt.Fprintf(w, "mean absolute error\t%.1f\n", result.MeanAbsoluteError)
+	fmt.Fprintf(w, "median absolute percent error\t%.1f%%\n", 100*result.MedianAbsolutePercentError)
+	fmt.Fprintf(w, "interval coverage\t%.1f%%\n", 100*result.IntervalCoverage)
+	fmt.Fprintf(w, "log r-squared\t%.3f\n", result.LogRSquared)
+	w.Flush()
+}
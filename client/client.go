@@ -3,17 +3,23 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
 
 	"github.com/steady-bytes/draft/pkg/chassis"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
@@ -30,10 +36,22 @@ type (
 		// GetLinkListing wraps Get() and automatically unmarshals the result into a Listing type with
 		// a Children type of Link.
 		GetLinkListing(ctx context.Context, url string, values url.Values) (listing models.Listing, err error)
+		// GetCommentListing wraps Get() and automatically unmarshals the result into a
+		// CommentListing type.
+		GetCommentListing(ctx context.Context, url string, values url.Values) (listing models.CommentListing, err error)
+		// GetUserAbout wraps Get() and automatically unmarshals the result into a UserAbout type.
+		GetUserAbout(ctx context.Context, url string, values url.Values) (about models.UserAbout, err error)
+		// GetSubredditAbout wraps Get() and automatically unmarshals the result into a
+		// SubredditAbout type.
+		GetSubredditAbout(ctx context.Context, url string, values url.Values) (about models.SubredditAbout, err error)
+		// TokenValid reports whether the configured access token was accepted by the latest
+		// response from the Reddit API.
+		TokenValid() bool
 	}
 	client struct {
 		logger      chassis.Logger
 		token       string
+		tokenValid  atomic.Bool
 		latestLimit time.Time
 		limiter     *rate.Limiter
 	}
@@ -44,21 +62,38 @@ const (
 	maxRate         = 2
 )
 
+// ErrNotFound is returned when the Reddit API responds with a 404.
+var ErrNotFound = errors.New("not found")
+
+var tracer = otel.Tracer("github.com/jgkawell/reddit-api-demo/client")
+
 func NewClient(logger chassis.Logger) Client {
 	token := chassis.GetConfig().GetString("reddit.accessToken")
 	if token == "" {
 		logger.Panic("no token provided in config")
 	}
 
-	return &client{
+	c := &client{
 		logger:      logger,
 		token:       token,
 		latestLimit: time.Now(),
 		limiter:     rate.NewLimiter(100/60, 1),
 	}
+	// assume the token is valid until the API tells us otherwise
+	c.tokenValid.Store(true)
+	return c
 }
 
 func (c *client) Get(ctx context.Context, url string, values url.Values) (response *http.Response, err error) {
+	ctx, span := tracer.Start(ctx, "reddit.get",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodGet,
+			semconv.URLFull(url),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return
@@ -77,27 +112,68 @@ func (c *client) Get(ctx context.Context, url string, values url.Values) (respon
 	if err != nil {
 		return
 	}
+	span.SetAttributes(
+		semconv.HTTPResponseStatusCode(response.StatusCode),
+		tracing.RateLimitUsedKey.String(response.Header.Get("X-RateLimit-Used")),
+		tracing.RateLimitRemainingKey.String(response.Header.Get("X-RateLimit-Remaining")),
+		tracing.RateLimitResetKey.String(response.Header.Get("X-RateLimit-Reset")),
+	)
+	// a 403 is also returned for private, banned and quarantined subreddits so only a 401 means
+	// the token itself is no longer valid
+	switch response.StatusCode {
+	case http.StatusUnauthorized:
+		c.tokenValid.Store(false)
+	case http.StatusOK:
+		c.tokenValid.Store(true)
+	}
 	go c.setRateLimit(response.Header)
 	return
 }
 
 func (c *client) GetLinkListing(ctx context.Context, url string, values url.Values) (listing models.Listing, err error) {
+	err = c.getJSON(ctx, url, values, &listing)
+	return
+}
+
+func (c *client) GetCommentListing(ctx context.Context, url string, values url.Values) (listing models.CommentListing, err error) {
+	err = c.getJSON(ctx, url, values, &listing)
+	return
+}
+
+func (c *client) GetUserAbout(ctx context.Context, url string, values url.Values) (about models.UserAbout, err error) {
+	err = c.getJSON(ctx, url, values, &about)
+	return
+}
+
+func (c *client) GetSubredditAbout(ctx context.Context, url string, values url.Values) (about models.SubredditAbout, err error) {
+	err = c.getJSON(ctx, url, values, &about)
+	return
+}
+
+// getJSON wraps Get() and unmarshals a successful response body into v.
+func (c *client) getJSON(ctx context.Context, url string, values url.Values, v any) error {
 	resp, err := c.Get(ctx, url, values)
 	if err != nil {
-		return
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from Reddit API: %s", resp.Status)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return
+		return err
 	}
 
-	err = json.Unmarshal(body, &listing)
-	if err != nil {
-		return
-	}
+	return json.Unmarshal(body, v)
+}
 
-	return
+func (c *client) TokenValid() bool {
+	return c.tokenValid.Load()
 }
 
 func (c *client) setRateLimit(header http.Header) {
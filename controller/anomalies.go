@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
+)
+
+type (
+	// anomaliesConfig enables flagging intervals whose number of new posts is unusually high or
+	// low compared to an exponentially weighted moving average of the previous intervals.
+	// Threshold is the number of standard deviations from the average an interval must be to
+	// be anomalous and Alpha is the weight given to the latest interval.
+	anomaliesConfig struct {
+		Enabled   bool
+		Interval  time.Duration
+		Threshold float64
+		Alpha     float64
+	}
+	// volumeBaseline is the moving average and variance of the number of posts per interval
+	// along with the latest anomaly state
+	volumeBaseline struct {
+		mean      float64
+		variance  float64
+		intervals int
+		anomaly   models.Anomaly
+	}
+)
+
+const (
+	defaultAnomaliesInterval  = time.Hour
+	defaultAnomaliesThreshold = 3
+	defaultAnomaliesAlpha     = 0.3
+	// the number of intervals the baseline needs before anomalies are flagged
+	minAnomalyIntervals = 6
+	// the number of intervals of volume history to keep
+	maxVolumePoints = 48
+	// posts older than this aren't new and are no longer remembered as counted
+	maxArrivalAge = 24 * time.Hour
+)
+
+// startAnomalies counts the new posts returned by the live poll in each interval forever and is
+// meant to be run on a background routine.
+func (p *processor) startAnomalies(ctx context.Context) {
+	interval := p.config.Anomalies.Interval
+	if interval <= 0 {
+		interval = defaultAnomaliesInterval
+	}
+	for {
+		time.Sleep(interval)
+		p.checkVolume(ctx, time.Now())
+	}
+}
+
+// Anomaly reports whether the latest interval had an unusual number of new posts along with
+// the recent volume history. Multireddits also keep a baseline for each of their subreddits
+// which is reported if a subreddit is given.
+func (p *processor) Anomaly(_ context.Context, subreddit string) (anomaly models.Anomaly, ok bool) {
+	if !p.config.Anomalies.Enabled {
+		return anomaly, false
+	}
+	key := p.config.key()
+	if subreddit != "" {
+		key = strings.ToLower(subreddit)
+	}
+	p.anomalyMu.RLock()
+	defer p.anomalyMu.RUnlock()
+	b, ok := p.volumes[key]
+	if !ok {
+		return models.Anomaly{Subreddit: key, Points: []models.VolumePoint{}}, true
+	}
+	anomaly = b.anomaly
+	anomaly.Points = append([]models.VolumePoint{}, b.anomaly.Points...)
+	return anomaly, true
+}
+
+// recordArrivals counts the links returned by the live poll that haven't been counted before.
+// Links are counted before any filters are applied and regardless of whether they are still
+// tracked so that the volume reflects what was posted.
+func (p *processor) recordArrivals(links []models.Link, now time.Time) {
+	if !p.config.Anomalies.Enabled {
+		return
+	}
+	p.anomalyMu.Lock()
+	defer p.anomalyMu.Unlock()
+	for _, link := range links {
+		if _, ok := p.arrived[link.Data.Name]; ok {
+			continue
+		}
+		created := link.Data.Created()
+		if link.Data.CreatedUTC == 0 {
+			created = now
+		}
+		if now.Sub(created) > maxArrivalAge {
+			continue
+		}
+		p.arrived[link.Data.Name] = created
+		p.arrivals[strings.ToLower(link.Data.Subreddit)]++
+	}
+}
+
+// checkVolume closes the current interval, updating the baseline of the target and (for
+// multireddits) of each of its subreddits with the number of new posts that arrived in it.
+func (p *processor) checkVolume(ctx context.Context, now time.Time) {
+	_, span := tracer.Start(ctx, "processor.anomalies")
+	span.SetAttributes(tracing.SubredditKey.String(p.config.Name))
+	defer span.End()
+
+	interval := p.config.Anomalies.Interval
+	if interval <= 0 {
+		interval = defaultAnomaliesInterval
+	}
+
+	p.anomalyMu.Lock()
+	arrivals := p.arrivals
+	p.arrivals = map[string]int{}
+	for name, created := range p.arrived {
+		if now.Sub(created) > maxArrivalAge {
+			delete(p.arrived, name)
+		}
+	}
+	total := 0
+	for _, count := range arrivals {
+		total += count
+	}
+	span.SetAttributes(tracing.ResultCountKey.Int(total))
+
+	counts := map[string]int{p.config.key(): total}
+	if p.config.kind == targetSubreddit && strings.Contains(p.config.Name, "+") {
+		for _, member := range strings.Split(p.config.Name, "+") {
+			member = strings.ToLower(member)
+			counts[member] = arrivals[member]
+		}
+	}
+	changes := []models.Anomaly{}
+	for key, count := range counts {
+		b, ok := p.volumes[key]
+		if !ok {
+			b = &volumeBaseline{anomaly: models.Anomaly{Subreddit: key}}
+			p.volumes[key] = b
+		}
+		if p.observeVolume(b, count, now, interval) {
+			changes = append(changes, b.anomaly)
+		}
+	}
+	p.anomalyMu.Unlock()
+
+	for _, anomaly := range changes {
+		if anomaly.State == models.VolumeSpike || anomaly.State == models.VolumeDrop {
+			p.alertVolume(ctx, anomaly, interval)
+		}
+	}
+}
+
+// observeVolume scores the interval's count against the baseline before adding it to the
+// baseline, returning whether the anomaly state changed. The caller must hold anomalyMu.
+func (p *processor) observeVolume(b *volumeBaseline, count int, now time.Time, interval time.Duration) (changed bool) {
+	threshold := p.config.Anomalies.Threshold
+	if threshold <= 0 {
+		threshold = defaultAnomaliesThreshold
+	}
+	alpha := p.config.Anomalies.Alpha
+	if alpha <= 0 || alpha > 1 {
+		alpha = defaultAnomaliesAlpha
+	}
+
+	point := models.VolumePoint{
+		Time:     now,
+		Count:    count,
+		Expected: b.mean,
+	}
+	state := models.VolumeWarmingUp
+	if b.intervals >= minAnomalyIntervals {
+		// counts are small so the deviation is floored to keep a single post from being a spike
+		point.ZScore = (float64(count) - b.mean) / max(math.Sqrt(b.variance), 1)
+		switch {
+		case point.ZScore >= threshold:
+			state = models.VolumeSpike
+		case point.ZScore <= -threshold:
+			state = models.VolumeDrop
+		default:
+			state = models.VolumeNormal
+		}
+	}
+	if b.intervals == 0 {
+		b.mean = float64(count)
+	} else {
+		diff := float64(count) - b.mean
+		b.mean += alpha * diff
+		b.variance = (1 - alpha) * (b.variance + alpha*diff*diff)
+	}
+	b.intervals++
+
+	changed = state != b.anomaly.State
+	b.anomaly.IntervalSeconds = int64(interval.Seconds())
+	b.anomaly.Count = count
+	b.anomaly.Expected = point.Expected
+	b.anomaly.StdDev = math.Sqrt(b.variance)
+	b.anomaly.ZScore = point.ZScore
+	if changed {
+		b.anomaly.State = state
+		b.anomaly.Since = now
+	}
+	b.anomaly.Points = append(b.anomaly.Points, point)
+	if len(b.anomaly.Points) > maxVolumePoints {
+		b.anomaly.Points = b.anomaly.Points[len(b.anomaly.Points)-maxVolumePoints:]
+	}
+	return changed
+}
+
+func (p *processor) alertVolume(ctx context.Context, anomaly models.Anomaly, interval time.Duration) {
+	if p.notifier == nil {
+		return
+	}
+	alert := models.Alert{
+		Type:   models.AlertVolumeSpike,
+		Target: p.config.key(),
+		Title:  fmt.Sprintf("%s surged to %d posts in the last %s (expected %.1f)", anomaly.Subreddit, anomaly.Count, interval, anomaly.Expected),
+		Time:   anomaly.Since,
+	}
+	if anomaly.State == models.VolumeDrop {
+		alert.Type = models.AlertVolumeDrop
+		alert.Title = fmt.Sprintf("%s went quiet with %d posts in the last %s (expected %.1f)", anomaly.Subreddit, anomaly.Count, interval, anomaly.Expected)
+	}
+	err := p.notifier.Notify(ctx, alert)
+	if err != nil {
+		p.logger.WithError(err).Warn("failed to send alert")
+	}
+}
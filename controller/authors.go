@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+type (
+	// authorIndex records which subreddits each author posts to across every subreddit and
+	// multireddit target so that the tracked communities can be related. Followed users and
+	// searches are left out since their posts aren't a sample of any community. Posts are keyed
+	// by their fullname so that a post tracked by more than one target is only counted once.
+	authorIndex struct {
+		mu      sync.RWMutex
+		posts   map[string]*authoredPost
+		authors map[string]*authorActivity
+	}
+	// authoredPost is an indexed post along with the targets that are tracking it
+	authoredPost struct {
+		author    string
+		subreddit string
+		targets   map[string]bool
+	}
+	// authorActivity is the number of tracked posts an author has made to each subreddit
+	authorActivity struct {
+		name       string
+		subreddits map[string]int
+	}
+)
+
+func newAuthorIndex() *authorIndex {
+	return &authorIndex{
+		mu:      sync.RWMutex{},
+		posts:   map[string]*authoredPost{},
+		authors: map[string]*authorActivity{},
+	}
+}
+
+// add records the post against its author on behalf of the target. Posts by deleted authors
+// can't be attributed to anyone and are left out.
+func (i *authorIndex) add(target string, link models.LinkData) {
+	if link.Author == "" || link.Author == models.DeletedAuthor {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if post, ok := i.posts[link.Name]; ok {
+		post.targets[target] = true
+		return
+	}
+	i.posts[link.Name] = &authoredPost{
+		author:    link.Author,
+		subreddit: link.Subreddit,
+		targets:   map[string]bool{target: true},
+	}
+
+	key := strings.ToLower(link.Author)
+	a, ok := i.authors[key]
+	if !ok {
+		a = &authorActivity{
+			name:       link.Author,
+			subreddits: map[string]int{},
+		}
+		i.authors[key] = a
+	}
+	a.subreddits[strings.ToLower(link.Subreddit)]++
+}
+
+// remove releases the target's references to the named posts. Posts are only dropped from the
+// index once no target is tracking them.
+func (i *authorIndex) remove(target string, names map[string]bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for name := range names {
+		post, ok := i.posts[name]
+		if !ok {
+			continue
+		}
+		delete(post.targets, target)
+		if len(post.targets) > 0 {
+			continue
+		}
+		delete(i.posts, name)
+
+		key := strings.ToLower(post.author)
+		a, ok := i.authors[key]
+		if !ok {
+			continue
+		}
+		subreddit := strings.ToLower(post.subreddit)
+		a.subreddits[subreddit]--
+		if a.subreddits[subreddit] <= 0 {
+			delete(a.subreddits, subreddit)
+		}
+		if len(a.subreddits) == 0 {
+			delete(i.authors, key)
+		}
+	}
+}
+
+// subreddits is the number of distinct subreddits the author has posted to.
+func (i *authorIndex) subreddits(author string) int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if a, ok := i.authors[strings.ToLower(author)]; ok {
+		return len(a.subreddits)
+	}
+	return 0
+}
+
+// overlap compares the sets of authors of every pair of subreddits that share at least one
+// author, most similar first. If a subreddit is given only the pairs that include it are
+// returned.
+func (i *authorIndex) overlap(subreddit string) []models.SubredditOverlap {
+	subreddit = strings.ToLower(subreddit)
+	authors := map[string]int{}
+	shared := map[[2]string]int{}
+	i.mu.RLock()
+	for _, a := range i.authors {
+		names := []string{}
+		for name := range a.subreddits {
+			authors[name]++
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for x := range names {
+			for y := x + 1; y < len(names); y++ {
+				shared[[2]string{names[x], names[y]}]++
+			}
+		}
+	}
+	i.mu.RUnlock()
+
+	result := []models.SubredditOverlap{}
+	for pair, count := range shared {
+		if subreddit != "" && pair[0] != subreddit && pair[1] != subreddit {
+			continue
+		}
+		// list the requested subreddit first
+		if pair[1] == subreddit {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		result = append(result, models.SubredditOverlap{
+			Subreddit:     pair[0],
+			Other:         pair[1],
+			Authors:       authors[pair[0]],
+			OtherAuthors:  authors[pair[1]],
+			SharedAuthors: count,
+			Similarity:    float64(count) / float64(authors[pair[0]]+authors[pair[1]]-count),
+		})
+	}
+	slices.SortFunc(result, func(a, b models.SubredditOverlap) int {
+		return cmp.Or(
+			cmpDesc(a.Similarity, b.Similarity),
+			b.SharedAuthors-a.SharedAuthors,
+			strings.Compare(a.Subreddit, b.Subreddit),
+			strings.Compare(a.Other, b.Other),
+		)
+	})
+	return result
+}
+
+// crossActive returns the authors that posted to more than one tracked subreddit, those active
+// in the most subreddits first.
+func (i *authorIndex) crossActive() []models.CrossActiveAuthor {
+	result := []models.CrossActiveAuthor{}
+	i.mu.RLock()
+	for _, a := range i.authors {
+		if len(a.subreddits) < 2 {
+			continue
+		}
+		author := models.CrossActiveAuthor{
+			Name:       a.name,
+			Subreddits: []models.UserSubredditStats{},
+		}
+		for name, count := range a.subreddits {
+			author.Subreddits = append(author.Subreddits, models.UserSubredditStats{Subreddit: name, PostCount: count})
+			author.PostCount += count
+		}
+		slices.SortFunc(author.Subreddits, func(a, b models.UserSubredditStats) int {
+			return cmp.Or(b.PostCount-a.PostCount, strings.Compare(a.Subreddit, b.Subreddit))
+		})
+		result = append(result, author)
+	}
+	i.mu.RUnlock()
+
+	slices.SortFunc(result, func(a, b models.CrossActiveAuthor) int {
+		return cmp.Or(
+			len(b.Subreddits)-len(a.Subreddits),
+			b.PostCount-a.PostCount,
+			strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)),
+		)
+	})
+	return result
+}
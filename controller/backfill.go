@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
+
+	"golang.org/x/time/rate"
+)
+
+type (
+	// backfillConfig enables collection of links published before the processor started. Until
+	// is an optional date (YYYY-MM-DD) or RFC3339 timestamp bounding how far back to go and
+	// Interval is the minimum time between page requests. Backfilling waits on its own limiter
+	// at that rate before taking from the shared client limiter so that it only ever uses a
+	// small share of the rate limit and leaves the rest to live polling.
+	backfillConfig struct {
+		Enabled  bool
+		Until    string
+		Top      bool
+		Interval time.Duration
+	}
+)
+
+const (
+	// Reddit will not page a listing beyond this many items
+	maxListingItems         = 1000
+	backfillPageSize        = 100
+	defaultBackfillInterval = 10 * time.Second
+	maxBackfillRetries      = 5
+)
+
+// startBackfill pages backwards through the configured listings one after the other, merging
+// results into the same store as live polling. It is meant to be run on a background routine.
+func (p *processor) startBackfill(ctx context.Context) {
+	until, err := parseUntil(p.config.Backfill.Until)
+	if err != nil {
+		p.logger.WithError(err).Error("invalid backfill bound, backfilling disabled")
+		return
+	}
+
+	listings := []string{"new"}
+	if p.config.Backfill.Top {
+		listings = append(listings, "top")
+	}
+
+	p.backfillMu.Lock()
+	for _, listing := range listings {
+		p.backfills = append(p.backfills, models.BackfillProgress{
+			Subreddit: p.config.Name,
+			Listing:   listing,
+			State:     models.BackfillPending,
+			Until:     until,
+		})
+	}
+	p.backfillMu.Unlock()
+
+	for i := range listings {
+		p.backfill(ctx, i, until)
+	}
+}
+
+// Backfill returns the progress of each listing being backfilled.
+func (p *processor) Backfill() []models.BackfillProgress {
+	p.backfillMu.RLock()
+	defer p.backfillMu.RUnlock()
+	progress := make([]models.BackfillProgress, len(p.backfills))
+	copy(progress, p.backfills)
+	return progress
+}
+
+// backfill pages through a single listing until it runs out of links, hits the listing limit or
+// passes the date bound.
+func (p *processor) backfill(ctx context.Context, i int, until time.Time) {
+	listing := p.backfillProgress(i).Listing
+	logger := p.logger.WithField("listing", listing)
+	logger.Info("starting backfill")
+	p.updateBackfill(i, func(b *models.BackfillProgress) {
+		b.State = models.BackfillRunning
+	})
+
+	var (
+		after    string
+		retries  int
+		interval = p.config.Backfill.Interval
+	)
+	if interval <= 0 {
+		interval = defaultBackfillInterval
+	}
+	limiter := rate.NewLimiter(rate.Every(interval), 1)
+
+	for seen := 0; seen < maxListingItems; {
+		if err := limiter.Wait(ctx); err != nil {
+			logger.WithError(err).Error("backfill cancelled")
+			return
+		}
+		links, next, err := p.backfillPage(ctx, listing, after)
+		if err != nil {
+			retries++
+			logger.WithError(err).Warn("failed to backfill page")
+			p.updateBackfill(i, func(b *models.BackfillProgress) {
+				b.LastError = err.Error()
+				if retries > maxBackfillRetries {
+					b.State = models.BackfillFailed
+				}
+			})
+			if retries > maxBackfillRetries {
+				logger.Error("giving up on backfill")
+				return
+			}
+			continue
+		}
+		retries = 0
+		seen += len(links)
+
+		// "new" is ordered by time so we can stop at the first link past the bound, other
+		// listings have to be checked in full
+		reachedBound := false
+		ingested := 0
+		oldest := time.Time{}
+		for _, link := range links {
+			created := link.Data.Created()
+			if !until.IsZero() && created.Before(until) {
+				reachedBound = listing == "new"
+				continue
+			}
+			if !p.filter.match(link.Data) || p.expired(link.Data, time.Now()) {
+				continue
+			}
+			p.processLink(ctx, link)
+			p.processUser(ctx, link)
+			ingested++
+			if oldest.IsZero() || created.Before(oldest) {
+				oldest = created
+			}
+		}
+		p.updateBackfill(i, func(b *models.BackfillProgress) {
+			b.Pages++
+			b.Links += ingested
+			if !oldest.IsZero() && (b.Oldest.IsZero() || oldest.Before(b.Oldest)) {
+				b.Oldest = oldest
+			}
+		})
+
+		if reachedBound || next == "" {
+			break
+		}
+		after = next
+	}
+
+	p.updateBackfill(i, func(b *models.BackfillProgress) {
+		b.State = models.BackfillComplete
+	})
+	logger.Info("backfill complete")
+}
+
+// backfillPage queries the API for a page of the given listing after the provided "after" link.
+func (p *processor) backfillPage(ctx context.Context, listing string, after string) (links []models.Link, next string, err error) {
+	ctx, span := tracer.Start(ctx, "processor.backfill")
+	span.SetAttributes(
+		tracing.SubredditKey.String(p.config.Name),
+		tracing.CursorKey.String(after),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	values := url.Values{
+		"limit": {strconv.Itoa(backfillPageSize)},
+	}
+	if after != "" {
+		values.Set("after", after)
+	}
+	if listing == "top" {
+		values.Set("t", "all")
+	}
+	result, err := p.client.GetLinkListing(ctx, p.listingURL(listing, values), values)
+	if err != nil {
+		return nil, "", err
+	}
+	span.SetAttributes(tracing.ResultCountKey.Int(len(result.Data.Children)))
+	if result.Data.After != nil {
+		next = *result.Data.After
+	}
+	return result.Data.Children, next, nil
+}
+
+func (p *processor) backfillProgress(i int) models.BackfillProgress {
+	p.backfillMu.RLock()
+	defer p.backfillMu.RUnlock()
+	return p.backfills[i]
+}
+
+func (p *processor) updateBackfill(i int, update func(b *models.BackfillProgress)) {
+	p.backfillMu.Lock()
+	defer p.backfillMu.Unlock()
+	update(&p.backfills[i])
+}
+
+// parseUntil accepts either a date or a full RFC3339 timestamp. An empty value means no bound.
+func parseUntil(until string) (time.Time, error) {
+	if until == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, until); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse %q as a date or timestamp: %w", until, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
+)
+
+type (
+	// commentsConfig enables polling of the subreddit's comment stream. Interval is the pause
+	// between polls so that comments don't take rate limit away from link polling. Comments
+	// younger than MaxAge are refreshed by their fullname every RefreshInterval so that their
+	// scores keep up after they drop out of the stream.
+	commentsConfig struct {
+		Enabled         bool
+		Interval        time.Duration
+		RefreshInterval time.Duration
+		MaxAge          time.Duration
+	}
+	commenter struct {
+		name  string
+		count int
+		score int
+	}
+)
+
+const (
+	defaultCommentsInterval        = 10 * time.Second
+	defaultCommentsRefreshInterval = 5 * time.Minute
+	defaultCommentsMaxAge          = 24 * time.Hour
+)
+
+// startComments polls the latest comments forever and is meant to be run on a background
+// routine. Each poll only asks for comments newer than the newest one already seen.
+func (p *processor) startComments(ctx context.Context) {
+	interval := p.config.Comments.Interval
+	if interval <= 0 {
+		interval = defaultCommentsInterval
+	}
+	var before string
+	for {
+		newest, err := p.processComments(ctx, before)
+		if err != nil {
+			p.logger.WithError(err).Warn("failed to list comments")
+			time.Sleep(minBackoff)
+			continue
+		}
+		if newest != "" {
+			before = newest
+		}
+		time.Sleep(interval)
+	}
+}
+
+// processComments lists the comments newer than "before" and stores them, returning the newest
+// comment seen (if any).
+func (p *processor) processComments(ctx context.Context, before string) (newest string, err error) {
+	ctx, span := tracer.Start(ctx, "processor.comments")
+	span.SetAttributes(
+		tracing.SubredditKey.String(p.config.Name),
+		tracing.CursorKey.String(before),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	values := url.Values{
+		"limit": {"100"},
+	}
+	if before != "" {
+		values.Set("before", before)
+	}
+	listing, err := p.client.GetCommentListing(ctx, p.listingURL("comments", values), values)
+	if err != nil {
+		return "", err
+	}
+	span.SetAttributes(tracing.ResultCountKey.Int(len(listing.Data.Children)))
+	if len(listing.Data.Children) == 0 {
+		return "", nil
+	}
+
+	// NOTE: this could be converted to a database call
+	p.commentsMu.Lock()
+	for _, c := range listing.Data.Children {
+		p.comments[c.Data.Name] = c
+		p.commentSentiment[c.Data.Name] = sentiment(c.Data.Body)
+	}
+	p.commentsMu.Unlock()
+
+	// listings are sorted newest first
+	return listing.Data.Children[0].Data.Name, nil
+}
+
+// startCommentScores refreshes the scores of the tracked comments forever and is meant to be
+// run on a background routine.
+func (p *processor) startCommentScores(ctx context.Context) {
+	interval := p.config.Comments.RefreshInterval
+	if interval <= 0 {
+		interval = defaultCommentsRefreshInterval
+	}
+	for {
+		time.Sleep(interval)
+		err := p.refreshComments(ctx, time.Now())
+		if err != nil {
+			p.logger.WithError(err).Warn("failed to refresh comments")
+		}
+	}
+}
+
+// refreshComments looks up every tracked comment younger than the max age by its fullname and
+// stores the latest version of it. Comments are only seen once in the stream (right after
+// they're posted) so without this their scores would never move.
+func (p *processor) refreshComments(ctx context.Context, now time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "processor.comments.refresh")
+	span.SetAttributes(tracing.SubredditKey.String(p.config.Name))
+	defer func() { tracing.End(span, err) }()
+
+	maxAge := p.config.Comments.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultCommentsMaxAge
+	}
+	names := []string{}
+	p.commentsMu.RLock()
+	for name, c := range p.comments {
+		if now.Sub(c.Data.Created()) <= maxAge {
+			names = append(names, name)
+		}
+	}
+	p.commentsMu.RUnlock()
+	span.SetAttributes(tracing.ResultCountKey.Int(len(names)))
+
+	for batch := range slices.Chunk(names, infoBatchSize) {
+		values := url.Values{
+			"id": {strings.Join(batch, ",")},
+		}
+		listing, err := p.client.GetCommentListing(ctx, infoURL, values)
+		if err != nil {
+			return err
+		}
+		p.commentsMu.Lock()
+		for _, c := range listing.Data.Children {
+			// the comment may have been evicted while it was being looked up
+			if _, ok := p.comments[c.Data.Name]; !ok {
+				continue
+			}
+			p.comments[c.Data.Name] = c
+			p.commentSentiment[c.Data.Name] = sentiment(c.Data.Body)
+		}
+		p.commentsMu.Unlock()
+	}
+	return nil
+}
+
+// commentStats returns the top comments by score along with the top commenters sorted both by
+// number of comments and by total score. If a subreddit is given only its comments are included.
+func (p *processor) commentStats(subreddit string) (comments []models.CommentStats, byCount []models.CommenterStats, byScore []models.CommenterStats) {
+	comments = []models.CommentStats{}
+	commenters := map[string]*commenter{}
+
+	p.commentsMu.RLock()
+	for _, c := range p.comments {
+		if subreddit != "" && !strings.EqualFold(c.Data.Subreddit, subreddit) {
+			continue
+		}
+		comments = append(comments, models.CommentStats{
+			Name:      c.Data.Name,
+			Author:    c.Data.Author,
+			Body:      c.Data.Body,
+			Score:     c.Data.Score,
+			Sentiment: p.commentSentiment[c.Data.Name],
+			LinkID:    c.Data.LinkID,
+			Created:   c.Data.Created(),
+		})
+		u, ok := commenters[c.Data.AuthorFullname]
+		if !ok {
+			u = &commenter{name: c.Data.Author}
+			commenters[c.Data.AuthorFullname] = u
+		}
+		u.count++
+		u.score += c.Data.Score
+	}
+	p.commentsMu.RUnlock()
+	slices.SortFunc(comments, func(a, b models.CommentStats) int {
+		return b.Score - a.Score
+	})
+
+	byCount = []models.CommenterStats{}
+	for _, u := range commenters {
+		byCount = append(byCount, models.CommenterStats{
+			Name:         u.name,
+			CommentCount: u.count,
+			TotalScore:   u.score,
+		})
+	}
+	byScore = slices.Clone(byCount)
+	slices.SortFunc(byCount, func(a, b models.CommenterStats) int {
+		return b.CommentCount - a.CommentCount
+	})
+	slices.SortFunc(byScore, func(a, b models.CommenterStats) int {
+		return b.TotalScore - a.TotalScore
+	})
+
+	return
+}
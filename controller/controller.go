@@ -1,13 +1,21 @@
 package controller
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/jgkawell/reddit-api-demo/alert"
 	"github.com/jgkawell/reddit-api-demo/client"
 	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
 	"github.com/steady-bytes/draft/pkg/chassis"
+	"go.opentelemetry.io/otel/metric"
 )
 
 type (
@@ -16,47 +24,502 @@ type (
 	// the application uses a single token restricted to the same limits.
 	Controller interface {
 		// Stats will return the current stats for the given subreddit.
-		Stats(ctx context.Context, subreddit string, limit int) (links []models.LinkStats, users []models.UserStats, err error)
-		// Start will read the configured subreddits and start a single Processor for each.
+		Stats(ctx context.Context, subreddit string, query models.StatsQuery) (stats models.Stats, err error)
+		// Trending will return the posts gaining upvotes the fastest in the given subreddit.
+		Trending(ctx context.Context, subreddit string, limit int) (links []models.LinkStats, err error)
+		// Health reports the status of every Processor along with the validity of the token.
+		// A Processor is unhealthy once it has failed or has gone longer than the configured
+		// threshold without a successful poll.
+		Health(ctx context.Context) models.Health
+		// Rankings will return how links have ranked on the sampled listings of the given subreddit.
+		Rankings(ctx context.Context, subreddit string, limit int) (rankings models.Rankings, err error)
+		// History will return the score trajectory of the given post from whichever Processor
+		// is tracking it.
+		History(ctx context.Context, fullname string) (history models.PostHistory, err error)
+		// User will return the account details of the given author (if enrichment is enabled)
+		// along with their stats in every subreddit they have posted to.
+		User(ctx context.Context, name string) (profile models.UserProfile, err error)
+		// Backtest will evaluate the accuracy of score predictions for the given subreddit.
+		Backtest(ctx context.Context, subreddit string) (backtest models.Backtest, err error)
+		// Backfill will return the progress of collecting historical links for the given subreddit.
+		Backfill(ctx context.Context, subreddit string) (progress []models.BackfillProgress, err error)
+		// Terms will return the most common and emerging terms in the titles of the given
+		// subreddit's posts.
+		Terms(ctx context.Context, subreddit string, query models.TermsQuery) (terms models.Terms, err error)
+		// Heatmap will return the number of posts and median score by day of the week and hour of
+		// the day for the given subreddit.
+		Heatmap(ctx context.Context, subreddit string, query models.HeatmapQuery) (heatmap models.Heatmap, err error)
+		// Reposts will return the most recent likely reposts in the given subreddit.
+		Reposts(ctx context.Context, subreddit string, limit int) (reposts []models.Repost, err error)
+		// Removed will return the most recently removed or deleted posts in the given subreddit.
+		Removed(ctx context.Context, subreddit string, limit int) (removals []models.Removal, err error)
+		// Overlap will return the similarity of the sets of authors of every pair of tracked
+		// subreddits that share authors, optionally only the pairs that include the given subreddit.
+		Overlap(ctx context.Context, subreddit string, limit int) []models.SubredditOverlap
+		// CrossActive will return the authors that posted to the most tracked subreddits.
+		CrossActive(ctx context.Context, limit int) []models.CrossActiveAuthor
+		// Anomalies will return whether the volume of new posts is unusual for the given target or,
+		// if no target is given, every target with anomaly detection enabled.
+		Anomalies(ctx context.Context, subreddit string) (anomalies []models.Anomaly, err error)
+		// Alerts will return the most recent alerts raised by any Processor.
+		Alerts(ctx context.Context, limit int) []models.Alert
+		// Start will read the configured subreddits, users and searches and start a single
+		// Processor for each. Followed users are keyed as "u/{name}" and searches as
+		// "search/{name}".
 		Start()
 	}
 	controller struct {
 		logger     chassis.Logger
+		client     client.Client
+		enricher   Enricher
+		notifier   alert.Notifier
+		reposts    *repostIndex
+		authors    *authorIndex
+		threshold  time.Duration
+		mu         sync.RWMutex
+		started    bool
 		processors map[string]Processor
 	}
-	subredditConfig struct {
-		Name  string
-		Start string
+	// targetConfig configures a single tracking target. Targets are subreddits unless they are
+	// read from the list of users to follow or the list of searches to run.
+	targetConfig struct {
+		Name      string
+		Start     string
+		Alerts    bool
+		Backfill  backfillConfig
+		Comments  commentsConfig
+		Listings  listingsConfig
+		Metadata  metadataConfig
+		Filters   models.LinkFilter
+		Terms     termsConfig
+		Removals  removalsConfig
+		Heatmap   heatmapConfig
+		Anomalies anomaliesConfig
+		Retention retentionConfig
+
+		// the query to run and the subreddit to restrict it to (if any) for searches
+		Query     string
+		Subreddit string
+
+		kind targetKind
+	}
+	healthConfig struct {
+		Threshold time.Duration
 	}
+	targetKind string
+)
+
+const (
+	targetSubreddit targetKind = "subreddit"
+	targetUser      targetKind = "user"
+	targetSearch    targetKind = "search"
 )
 
+const defaultHealthThreshold = 5 * time.Minute
+
+// ErrNotFound is returned when the requested item isn't tracked by any Processor.
+var ErrNotFound = errors.New("not found")
+
 func NewController(logger chassis.Logger) Controller {
 	return &controller{
 		logger:     logger,
+		threshold:  defaultHealthThreshold,
 		processors: map[string]Processor{},
 	}
 }
 
-func (c *controller) Stats(ctx context.Context, subreddit string, limit int) (links []models.LinkStats, users []models.UserStats, err error) {
-	p, ok := c.processors[subreddit]
-	if !ok {
-		return nil, nil, errors.New("subreddit not configured")
+func (c *controller) Stats(ctx context.Context, subreddit string, query models.StatsQuery) (stats models.Stats, err error) {
+	ctx, span := tracer.Start(ctx, "controller.stats")
+	span.SetAttributes(
+		tracing.SubredditKey.String(subreddit),
+		tracing.LimitKey.Int(query.Limit),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	p, member, err := c.target(subreddit)
+	if err != nil {
+		return stats, err
+	}
+	query.Subreddit = cmp.Or(member, query.Subreddit)
+	return p.Stats(ctx, query)
+}
+
+func (c *controller) Trending(ctx context.Context, subreddit string, limit int) (links []models.LinkStats, err error) {
+	p, member, err := c.target(subreddit)
+	if err != nil {
+		return nil, err
+	}
+	return p.Trending(ctx, member, limit), nil
+}
+
+func (c *controller) Rankings(ctx context.Context, subreddit string, limit int) (rankings models.Rankings, err error) {
+	p, member, err := c.target(subreddit)
+	if err != nil {
+		return rankings, err
+	}
+	return p.Rankings(ctx, member, limit), nil
+}
+
+func (c *controller) History(ctx context.Context, fullname string) (history models.PostHistory, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, p := range c.processors {
+		if history, ok := p.History(ctx, fullname); ok {
+			return history, nil
+		}
+	}
+	return history, ErrNotFound
+}
+
+func (c *controller) User(ctx context.Context, name string) (profile models.UserProfile, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	profile = models.UserProfile{
+		Name:       name,
+		Subreddits: []models.UserSubredditStats{},
+	}
+	for subreddit, p := range c.processors {
+		stats, ok := p.User(ctx, name)
+		if !ok {
+			continue
+		}
+		profile.Subreddits = append(profile.Subreddits, models.UserSubredditStats{
+			Subreddit: subreddit,
+			PostCount: stats.PostCount,
+		})
+	}
+	if len(profile.Subreddits) == 0 {
+		return profile, ErrNotFound
+	}
+	slices.SortFunc(profile.Subreddits, func(a, b models.UserSubredditStats) int {
+		return b.PostCount - a.PostCount
+	})
+	if c.enricher != nil {
+		profile.Account = c.enricher.Account(name)
+	}
+
+	return profile, nil
+}
+
+func (c *controller) Terms(ctx context.Context, subreddit string, query models.TermsQuery) (terms models.Terms, err error) {
+	p, member, err := c.target(subreddit)
+	if err != nil {
+		return terms, err
+	}
+	query.Subreddit = cmp.Or(member, query.Subreddit)
+	return p.Terms(ctx, query), nil
+}
+
+func (c *controller) Heatmap(ctx context.Context, subreddit string, query models.HeatmapQuery) (heatmap models.Heatmap, err error) {
+	p, member, err := c.target(subreddit)
+	if err != nil {
+		return heatmap, err
+	}
+	query.Subreddit = cmp.Or(member, query.Subreddit)
+	return p.Heatmap(ctx, query)
+}
+
+func (c *controller) Reposts(ctx context.Context, subreddit string, limit int) (reposts []models.Repost, err error) {
+	p, member, err := c.target(subreddit)
+	if err != nil {
+		return nil, err
+	}
+	return p.Reposts(ctx, member, limit), nil
+}
+
+func (c *controller) Removed(ctx context.Context, subreddit string, limit int) (removals []models.Removal, err error) {
+	p, member, err := c.target(subreddit)
+	if err != nil {
+		return nil, err
+	}
+	return p.Removed(ctx, member, limit), nil
+}
+
+func (c *controller) Overlap(_ context.Context, subreddit string, limit int) []models.SubredditOverlap {
+	c.mu.RLock()
+	authors := c.authors
+	c.mu.RUnlock()
+	if authors == nil {
+		return []models.SubredditOverlap{}
+	}
+	return truncate(authors.overlap(subreddit), limit)
+}
+
+func (c *controller) CrossActive(_ context.Context, limit int) []models.CrossActiveAuthor {
+	c.mu.RLock()
+	authors := c.authors
+	c.mu.RUnlock()
+	if authors == nil {
+		return []models.CrossActiveAuthor{}
+	}
+	return truncate(authors.crossActive(), limit)
+}
+
+func (c *controller) Anomalies(ctx context.Context, subreddit string) (anomalies []models.Anomaly, err error) {
+	anomalies = []models.Anomaly{}
+	if subreddit != "" {
+		// subreddits that are only tracked as part of a multireddit have their own baseline
+		p, member, err := c.target(subreddit)
+		if err != nil {
+			return nil, err
+		}
+		if anomaly, ok := p.Anomaly(ctx, member); ok {
+			anomalies = append(anomalies, anomaly)
+		}
+		return anomalies, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, p := range c.processors {
+		if anomaly, ok := p.Anomaly(ctx, ""); ok {
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+	slices.SortFunc(anomalies, func(a, b models.Anomaly) int {
+		return strings.Compare(a.Subreddit, b.Subreddit)
+	})
+	return anomalies, nil
+}
+
+func (c *controller) Alerts(_ context.Context, limit int) []models.Alert {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.notifier == nil {
+		return []models.Alert{}
+	}
+	return c.notifier.Recent(limit)
+}
+
+func (c *controller) Backtest(ctx context.Context, subreddit string) (backtest models.Backtest, err error) {
+	p, member, err := c.target(subreddit)
+	if err != nil {
+		return backtest, err
+	}
+	return p.Backtest(ctx, member)
+}
+
+func (c *controller) Backfill(_ context.Context, subreddit string) (progress []models.BackfillProgress, err error) {
+	// members of a multireddit are backfilled along with the rest of it
+	p, _, err := c.target(subreddit)
+	if err != nil {
+		return nil, err
 	}
-	return p.Stats(ctx, limit)
+	return p.Backfill(), nil
+}
+
+func (c *controller) Health(_ context.Context) models.Health {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	health := models.Health{
+		Healthy:    true,
+		Ready:      c.started,
+		TokenValid: c.client != nil && c.client.TokenValid(),
+		Processors: []models.ProcessorStatus{},
+	}
+	if !health.TokenValid {
+		health.Ready = false
+	}
+
+	for _, p := range c.processors {
+		status := p.Status()
+		status.Healthy = c.healthy(status)
+		if !status.Healthy {
+			health.Healthy = false
+		}
+		if status.State != models.StateRunning {
+			health.Ready = false
+		}
+		health.Processors = append(health.Processors, status)
+	}
+	slices.SortFunc(health.Processors, func(a, b models.ProcessorStatus) int {
+		return strings.Compare(a.Subreddit, b.Subreddit)
+	})
+
+	return health
 }
 
 func (c *controller) Start() {
-	config := []subredditConfig{}
+	config := []targetConfig{}
 	err := chassis.GetConfig().UnmarshalKey("reddit.subreddits", &config)
 	if err != nil {
 		c.logger.WithError(err).Error("failed to read subreddit config")
 		os.Exit(1)
 	}
+	for i := range config {
+		config[i].kind = targetSubreddit
+	}
+
+	users := []targetConfig{}
+	err = chassis.GetConfig().UnmarshalKey("reddit.users", &users)
+	if err != nil {
+		c.logger.WithError(err).Error("failed to read user config")
+		os.Exit(1)
+	}
+	for _, u := range users {
+		u.kind = targetUser
+		config = append(config, u)
+	}
 
-	client := client.NewClient(c.logger)
-	for _, subreddit := range config {
-		p := NewProcessor(c.logger, client, subreddit)
+	searches := []targetConfig{}
+	err = chassis.GetConfig().UnmarshalKey("reddit.searches", &searches)
+	if err != nil {
+		c.logger.WithError(err).Error("failed to read search config")
+		os.Exit(1)
+	}
+	for _, s := range searches {
+		s.kind = targetSearch
+		config = append(config, s)
+	}
+
+	health := healthConfig{}
+	err = chassis.GetConfig().UnmarshalKey("reddit.health", &health)
+	if err != nil {
+		c.logger.WithError(err).Error("failed to read health config")
+		os.Exit(1)
+	}
+
+	enrichment := enrichmentConfig{}
+	err = chassis.GetConfig().UnmarshalKey("reddit.enrichment", &enrichment)
+	if err != nil {
+		c.logger.WithError(err).Error("failed to read enrichment config")
+		os.Exit(1)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if health.Threshold > 0 {
+		c.threshold = health.Threshold
+	}
+	c.client = client.NewClient(c.logger)
+	c.notifier = alert.NewNotifier(c.logger)
+	c.reposts = newRepostIndex()
+	c.authors = newAuthorIndex()
+	if enrichment.Enabled {
+		c.enricher = NewEnricher(c.logger, c.client, enrichment)
+		go c.enricher.Start()
+	}
+	for _, target := range config {
+		p := NewProcessor(c.logger, c.client, c.enricher, c.notifier, c.reposts, c.authors, target)
 		go p.Start()
-		c.processors[subreddit.Name] = p
+		c.processors[target.key()] = p
+	}
+	err = c.registerMetrics(meter)
+	if err != nil {
+		c.logger.WithError(err).Warn("failed to register metrics")
+	}
+	c.started = true
+}
+
+// registerMetrics reports the tracked data counts and memory estimate of every Processor as
+// gauges on the meter so that memory growth can be watched without scraping the health checks.
+func (c *controller) registerMetrics(meter metric.Meter) error {
+	links, err := meter.Int64ObservableGauge("reddit.processor.links",
+		metric.WithDescription("The number of tracked posts"))
+	if err != nil {
+		return err
+	}
+	users, err := meter.Int64ObservableGauge("reddit.processor.users",
+		metric.WithDescription("The number of tracked authors"))
+	if err != nil {
+		return err
+	}
+	memory, err := meter.Int64ObservableGauge("reddit.processor.memory",
+		metric.WithDescription("A rough estimate of the memory used by the tracked data"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		for _, p := range c.processors {
+			status := p.Status()
+			subreddit := metric.WithAttributes(tracing.SubredditKey.String(status.Subreddit))
+			o.ObserveInt64(links, int64(status.Links), subreddit)
+			o.ObserveInt64(users, int64(status.Users), subreddit)
+			o.ObserveInt64(memory, status.MemoryBytes, subreddit)
+		}
+		return nil
+	}, links, users, memory)
+	return err
+}
+
+// processor looks up the Processor for the given subreddit.
+func (c *controller) processor(subreddit string) (Processor, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p, ok := c.processors[subreddit]
+	if !ok {
+		return nil, errors.New("subreddit not configured")
+	}
+	return p, nil
+}
+
+// target looks up the Processor for the given subreddit. Subreddits that are only tracked as
+// part of a multireddit are served from its bucket, in which case the subreddit is returned as
+// the member to narrow the results down to.
+func (c *controller) target(subreddit string) (p Processor, member string, err error) {
+	p, err = c.processor(subreddit)
+	if err == nil {
+		return p, "", nil
+	}
+	p, ok := c.multireddit(subreddit)
+	if !ok {
+		return nil, "", err
+	}
+	return p, subreddit, nil
+}
+
+// multireddit finds the Processor of a multireddit that includes the subreddit.
+func (c *controller) multireddit(subreddit string) (Processor, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for key, p := range c.processors {
+		if !strings.Contains(key, "+") {
+			continue
+		}
+		for _, name := range strings.Split(key, "+") {
+			if strings.EqualFold(name, subreddit) {
+				return p, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// healthy checks the status of a Processor against the configured threshold. Processors that
+// have never polled successfully are measured from when they entered their current state.
+func (c *controller) healthy(status models.ProcessorStatus) bool {
+	if status.State == models.StateFailed {
+		return false
+	}
+	last := status.LastPoll
+	if last.IsZero() {
+		last = status.StateSince
+	}
+	return time.Since(last) <= c.threshold
+}
+
+// combined reports whether the target is a multireddit (e.g. "golang+rust") or one of Reddit's
+// combined listings (r/all and r/popular) whose links come from many subreddits.
+func (t targetConfig) combined() bool {
+	if t.kind != targetSubreddit {
+		return false
+	}
+	return strings.Contains(t.Name, "+") || strings.EqualFold(t.Name, "all") || strings.EqualFold(t.Name, "popular")
+}
+
+// key is the name the target's Processor is registered under.
+func (t targetConfig) key() string {
+	switch t.kind {
+	case targetUser:
+		return "u/" + t.Name
+	case targetSearch:
+		return "search/" + t.Name
+	default:
+		return t.Name
 	}
 }
@@ -3,13 +3,24 @@ package controller
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	clientpkg "github.com/jgkawell/reddit-api-demo/client"
 	"github.com/jgkawell/reddit-api-demo/mocks"
 	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 
 	"github.com/steady-bytes/draft/pkg/loggers/zerolog"
 )
@@ -52,7 +63,7 @@ var (
 func Test_Processor(t *testing.T) {
 	logger := zerolog.New()
 	client := mocks.NewClient(t)
-	config := subredditConfig{}
+	config := targetConfig{}
 	proc := &processor{
 		logger: logger,
 		client: client,
@@ -62,6 +73,20 @@ func Test_Processor(t *testing.T) {
 		links:   make(map[string]models.Link),
 		usersMu: sync.RWMutex{},
 		users:   make(map[string]user),
+
+		historyMu:    sync.RWMutex{},
+		history:      make(map[string][]models.ScorePoint),
+		observations: make(map[string]*observation),
+
+		removalsMu: sync.RWMutex{},
+		removals:   make(map[string]models.Removal),
+
+		repostIndex: newRepostIndex(),
+		repostsMu:   sync.RWMutex{},
+		reposts:     make(map[string]models.Repost),
+
+		termsMu: sync.RWMutex{},
+		titles:  make(map[string]titleTerms),
 	}
 
 	tests := []struct {
@@ -100,14 +125,17 @@ func Test_Processor(t *testing.T) {
 			expectedLinks: []models.LinkStats{
 				{
 					Name:    l3.Data.Name,
+					Type:    models.PostLink,
 					UpVotes: l3.Data.Ups,
 				},
 				{
 					Name:    l2.Data.Name,
+					Type:    models.PostLink,
 					UpVotes: l2.Data.Ups,
 				},
 				{
 					Name:    l1.Data.Name,
+					Type:    models.PostLink,
 					UpVotes: l1.Data.Ups,
 				},
 			},
@@ -136,6 +164,7 @@ func Test_Processor(t *testing.T) {
 			expectedLinks: []models.LinkStats{
 				{
 					Name:    l1.Data.Name,
+					Type:    models.PostLink,
 					UpVotes: l1.Data.Ups,
 				},
 			},
@@ -160,6 +189,7 @@ func Test_Processor(t *testing.T) {
 			expectedLinks: []models.LinkStats{
 				{
 					Name:    l1.Data.Name,
+					Type:    models.PostLink,
 					UpVotes: l1.Data.Ups,
 				},
 			},
@@ -187,6 +217,7 @@ func Test_Processor(t *testing.T) {
 			expectedLinks: []models.LinkStats{
 				{
 					Name:    l3.Data.Name,
+					Type:    models.PostLink,
 					UpVotes: l3.Data.Ups,
 				},
 			},
@@ -205,10 +236,10 @@ func Test_Processor(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.processor.links = tc.links
 			tc.processor.users = tc.users
-			links, users, err := tc.processor.Stats(ctx, tc.limit)
+			stats, err := tc.processor.Stats(ctx, models.StatsQuery{Limit: tc.limit})
 
-			assert.Equal(t, links, tc.expectedLinks)
-			assert.Equal(t, users, tc.expectedUsers)
+			assert.Equal(t, stats.Posts, tc.expectedLinks)
+			assert.Equal(t, stats.Users, tc.expectedUsers)
 			assert.Equal(t, err, tc.expectedErr)
 
 		})
@@ -219,7 +250,7 @@ func Test_ProcessorInit(t *testing.T) {
 	ctx := context.Background()
 	logger := zerolog.New()
 	client := mocks.NewClient(t)
-	config := subredditConfig{
+	config := targetConfig{
 		Name: "test",
 	}
 	proc := &processor{
@@ -270,7 +301,7 @@ func Test_ProcessorInit(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			client.On("GetLinkListing", ctx, mock.Anything, mock.Anything).Once().Return(tc.listingResponse, tc.expectedErr)
+			client.On("GetLinkListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(tc.listingResponse, tc.expectedErr)
 
 			start, err := tc.processor.init(ctx)
 
@@ -285,7 +316,7 @@ func Test_ProcessorProcess(t *testing.T) {
 	ctx := context.Background()
 	logger := zerolog.New()
 	client := mocks.NewClient(t)
-	config := subredditConfig{
+	config := targetConfig{
 		Name: "test",
 		Start: "example",
 	}
@@ -298,6 +329,20 @@ func Test_ProcessorProcess(t *testing.T) {
 		links:   make(map[string]models.Link),
 		usersMu: sync.RWMutex{},
 		users:   make(map[string]user),
+
+		historyMu:    sync.RWMutex{},
+		history:      make(map[string][]models.ScorePoint),
+		observations: make(map[string]*observation),
+
+		removalsMu: sync.RWMutex{},
+		removals:   make(map[string]models.Removal),
+
+		repostIndex: newRepostIndex(),
+		repostsMu:   sync.RWMutex{},
+		reposts:     make(map[string]models.Repost),
+
+		termsMu: sync.RWMutex{},
+		titles:  make(map[string]titleTerms),
 	}
 
 	tests := []struct {
@@ -371,9 +416,1758 @@ func Test_ProcessorProcess(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			client.On("GetLinkListing", ctx, mock.Anything, mock.Anything).Once().Return(tc.listingResponse, tc.expectedErr)
+			client.On("GetLinkListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(tc.listingResponse, tc.expectedErr)
 
 			tc.processor.process(ctx)
 		})
 	}
 }
+
+func Test_ProcessorFollowUser(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	notifier := mocks.NewNotifier(t)
+	config := targetConfig{
+		Name:   "spez",
+		Alerts: true,
+		kind:   targetUser,
+	}
+	proc := NewProcessor(logger, client, nil, notifier, nil, nil, config).(*processor)
+	assert.Equal(t, "u/spez", proc.Status().Subreddit)
+
+	link := func(name, subreddit string, ups int) models.Link {
+		return models.Link{Data: models.LinkData{Name: name, Subreddit: subreddit, Author: "spez", Ups: ups, Permalink: "/r/" + subreddit + "/" + name}}
+	}
+	proc.links["l1"] = link("l1", "announcements", 10)
+
+	alerted := make(chan models.Alert, 1)
+	client.On("GetLinkListing", mock.Anything, "https://oauth.reddit.com/user/spez/submitted", mock.MatchedBy(func(values url.Values) bool {
+		return values.Get("sort") == "new"
+	})).Once().Return(models.Listing{
+		Data: models.ListingData{
+			Children: []models.Link{link("l1", "announcements", 10), link("l2", "reddit", 30)},
+		},
+	}, nil)
+	notifier.On("Notify", mock.Anything, mock.Anything).Once().Run(func(args mock.Arguments) {
+		alerted <- args.Get(1).(models.Alert)
+	}).Return(nil)
+
+	proc.process(ctx)
+
+	// only links that weren't already tracked raise an alert
+	select {
+	case a := <-alerted:
+		assert.Equal(t, models.AlertNewPost, a.Type)
+		assert.Equal(t, "u/spez", a.Target)
+		assert.Equal(t, "https://www.reddit.com/r/reddit/l2", a.URL)
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert for the new post")
+	}
+
+	proc.linksMu.Lock()
+	proc.links["l2"] = link("l2", "reddit", 30)
+	proc.links["l3"] = link("l3", "reddit", 50)
+	proc.linksMu.Unlock()
+
+	stats, err := proc.Stats(ctx, models.StatsQuery{Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.SubredditBreakdown{
+		{Subreddit: "reddit", PostCount: 2, TotalUpVotes: 80, AverageUpVotes: 40},
+		{Subreddit: "announcements", PostCount: 1, TotalUpVotes: 10, AverageUpVotes: 10},
+	}, stats.Subreddits)
+}
+
+func Test_ProcessorSearch(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	notifier := mocks.NewNotifier(t)
+	config := targetConfig{
+		Name:      "product",
+		Alerts:    true,
+		Query:     "reddit api",
+		Subreddit: "golang",
+		kind:      targetSearch,
+	}
+	proc := NewProcessor(logger, client, nil, notifier, nil, nil, config).(*processor)
+	assert.Equal(t, "search/product", proc.Status().Subreddit)
+
+	search := mock.MatchedBy(func(values url.Values) bool {
+		return values.Get("q") == "reddit api" && values.Get("sort") == "new" && values.Get("restrict_sr") == "true" && !values.Has("before")
+	})
+	client.On("GetLinkListing", mock.Anything, "https://oauth.reddit.com/r/golang/search", search).Once().Return(models.Listing{
+		Data: models.ListingData{Children: []models.Link{l2, l1}},
+	}, nil)
+	start, err := proc.init(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "l2", start)
+
+	// results that were matching at startup and repeats are dropped
+	client.On("GetLinkListing", mock.Anything, "https://oauth.reddit.com/r/golang/search", search).Once().Return(models.Listing{
+		Data: models.ListingData{Children: []models.Link{l3, l3, l2, l1}},
+	}, nil)
+	alerted := make(chan models.Alert, 2)
+	notifier.On("Notify", mock.Anything, mock.Anything).Once().Run(func(args mock.Arguments) {
+		alerted <- args.Get(1).(models.Alert)
+	}).Return(nil)
+
+	proc.process(ctx)
+
+	select {
+	case a := <-alerted:
+		assert.Equal(t, models.AlertNewMatch, a.Type)
+		assert.Equal(t, "search/product", a.Target)
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert for the new match")
+	}
+	assert.Eventually(t, func() bool { return proc.tracked("l3") }, time.Second, 10*time.Millisecond)
+	assert.False(t, proc.tracked("l1"))
+	assert.False(t, proc.tracked("l2"))
+}
+
+func Test_ProcessorMultireddit(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, targetConfig{Name: "golang+rust"}).(*processor)
+	assert.True(t, proc.config.combined())
+
+	link := func(name, subreddit, author string, ups int) models.Link {
+		return models.Link{Data: models.LinkData{Name: name, Subreddit: subreddit, Author: author, AuthorFullname: author, Ups: ups}}
+	}
+	for _, l := range []models.Link{
+		link("l1", "golang", "u1", 10),
+		link("l2", "golang", "u2", 30),
+		link("l3", "rust", "u1", 50),
+	} {
+		proc.processLink(ctx, l)
+		proc.processUser(ctx, l)
+	}
+
+	tests := []struct {
+		name               string
+		query              models.StatsQuery
+		expectedPosts      []string
+		expectedUsers      []models.UserStats
+		expectedSubreddits []models.SubredditBreakdown
+		expectedGrouped    map[string][]string
+	}{
+		{
+			name:          "combined",
+			query:         models.StatsQuery{Limit: 5},
+			expectedPosts: []string{"l3", "l2", "l1"},
+			expectedUsers: []models.UserStats{{Name: "u1", PostCount: 2}, {Name: "u2", PostCount: 1}},
+			expectedSubreddits: []models.SubredditBreakdown{
+				{Subreddit: "golang", PostCount: 2, TotalUpVotes: 40, AverageUpVotes: 20},
+				{Subreddit: "rust", PostCount: 1, TotalUpVotes: 50, AverageUpVotes: 50},
+			},
+		},
+		{
+			name:          "single subreddit",
+			query:         models.StatsQuery{Limit: 5, Subreddit: "rust"},
+			expectedPosts: []string{"l3"},
+			expectedUsers: []models.UserStats{{Name: "u1", PostCount: 1}},
+			expectedSubreddits: []models.SubredditBreakdown{
+				{Subreddit: "rust", PostCount: 1, TotalUpVotes: 50, AverageUpVotes: 50},
+			},
+		},
+		{
+			name:          "grouped",
+			query:         models.StatsQuery{Limit: 1, Group: models.GroupSubreddit},
+			expectedPosts: []string{"l3"},
+			expectedUsers: []models.UserStats{{Name: "u1", PostCount: 2}},
+			expectedSubreddits: []models.SubredditBreakdown{
+				{Subreddit: "golang", PostCount: 2, TotalUpVotes: 40, AverageUpVotes: 20, Users: []models.UserStats{{Name: "u1", PostCount: 1}}},
+				{Subreddit: "rust", PostCount: 1, TotalUpVotes: 50, AverageUpVotes: 50, Users: []models.UserStats{{Name: "u1", PostCount: 1}}},
+			},
+			expectedGrouped: map[string][]string{"golang": {"l2"}, "rust": {"l3"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stats, err := proc.Stats(ctx, tc.query)
+			assert.NoError(t, err)
+
+			posts := []string{}
+			for _, l := range stats.Posts {
+				posts = append(posts, l.Name)
+			}
+			assert.Equal(t, tc.expectedPosts, posts)
+			assert.Equal(t, tc.expectedUsers, stats.Users)
+
+			for i, b := range stats.Subreddits {
+				if tc.expectedGrouped == nil {
+					assert.Nil(t, b.Posts)
+					continue
+				}
+				grouped := []string{}
+				for _, l := range b.Posts {
+					grouped = append(grouped, l.Name)
+				}
+				assert.Equal(t, tc.expectedGrouped[b.Subreddit], grouped)
+				stats.Subreddits[i].Posts = nil
+			}
+			assert.Equal(t, tc.expectedSubreddits, stats.Subreddits)
+		})
+	}
+}
+
+func Test_LinkFilter(t *testing.T) {
+	yes, no := true, false
+	link := models.LinkData{
+		Subreddit:       "pics",
+		Title:           "My cat sitting in a box",
+		Author:          "u1",
+		Domain:          "i.redd.it",
+		LinkFlairText:   "OC",
+		AuthorFlairText: "Moderator",
+		PostHint:        "image",
+		Ups:             100,
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.LinkFilter
+		link     models.LinkData
+		expected bool
+	}{
+		{name: "empty", link: link, expected: true},
+		{name: "title", filter: models.LinkFilter{Title: "\\bCAT\\b"}, link: link, expected: true},
+		{name: "title mismatch", filter: models.LinkFilter{Title: "dog"}, link: link, expected: false},
+		{name: "exclude title", filter: models.LinkFilter{ExcludeTitle: "box$"}, link: link, expected: false},
+		{name: "flair", filter: models.LinkFilter{Flairs: []string{"oc", "meme"}}, link: link, expected: true},
+		{name: "exclude flair", filter: models.LinkFilter{ExcludeFlairs: []string{"OC"}}, link: link, expected: false},
+		{name: "author flair", filter: models.LinkFilter{AuthorFlairs: []string{"moderator"}}, link: link, expected: true},
+		{name: "exclude author flair", filter: models.LinkFilter{ExcludeAuthorFlairs: []string{"Moderator"}}, link: link, expected: false},
+		{name: "domain", filter: models.LinkFilter{Domains: []string{"imgur.com"}}, link: link, expected: false},
+		{name: "exclude domain", filter: models.LinkFilter{ExcludeDomains: []string{"imgur.com"}}, link: link, expected: true},
+		{name: "type", filter: models.LinkFilter{Types: []models.PostType{models.PostImage}}, link: link, expected: true},
+		{name: "type mismatch", filter: models.LinkFilter{Types: []models.PostType{models.PostSelf}}, link: link, expected: false},
+		{name: "author allow list", filter: models.LinkFilter{Authors: []string{"u2"}}, link: link, expected: false},
+		{name: "author deny list", filter: models.LinkFilter{ExcludeAuthors: []string{"U1"}}, link: link, expected: false},
+		{name: "not nsfw", filter: models.LinkFilter{NSFW: &no}, link: link, expected: true},
+		{name: "nsfw only", filter: models.LinkFilter{NSFW: &yes}, link: link, expected: false},
+		{name: "not stickied", filter: models.LinkFilter{Stickied: &no}, link: models.LinkData{Stickied: true}, expected: false},
+		{name: "min upvotes", filter: models.LinkFilter{MinUpVotes: 101}, link: link, expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := newLinkFilter(tc.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, filter.match(tc.link))
+		})
+	}
+
+	_, err := newLinkFilter(models.LinkFilter{Title: "("})
+	assert.Error(t, err)
+}
+
+func Test_ProcessorStatus(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	config := targetConfig{
+		Name:  "test",
+		Start: "example",
+	}
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, config).(*processor)
+
+	tests := []struct {
+		name            string
+		listingErr      error
+		expectedState   models.ProcessorState
+		expectedError   string
+		expectedBackoff time.Duration
+	}{
+		{
+			name:            "first failure",
+			listingErr:      errors.New("failed to call api"),
+			expectedState:   models.StateBackingOff,
+			expectedError:   "failed to call api",
+			expectedBackoff: minBackoff,
+		},
+		{
+			name:            "second failure",
+			listingErr:      errors.New("failed to call api"),
+			expectedState:   models.StateBackingOff,
+			expectedError:   "failed to call api",
+			expectedBackoff: 2 * minBackoff,
+		},
+		{
+			name:            "recovered",
+			listingErr:      nil,
+			expectedState:   models.StateRunning,
+			expectedError:   "failed to call api",
+			expectedBackoff: 0,
+		},
+	}
+
+	assert.Equal(t, models.StateInitializing, proc.Status().State)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client.On("GetLinkListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(models.Listing{}, tc.listingErr)
+
+			proc.process(ctx)
+
+			status := proc.Status()
+			assert.Equal(t, tc.expectedState, status.State)
+			assert.Equal(t, tc.expectedError, status.LastError)
+			assert.Equal(t, tc.expectedBackoff, proc.backoff())
+		})
+	}
+}
+
+func Test_ProcessorStartingLink(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, targetConfig{Name: "test"}).(*processor)
+
+	client.On("GetLinkListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(models.Listing{}, errors.New("failed to call api"))
+	client.On("GetLinkListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(models.Listing{
+		Data: models.ListingData{
+			Children: []models.Link{{Data: models.LinkData{Name: "l1"}}},
+		},
+	}, nil)
+
+	// a failed init is retried rather than failing the processor for good
+	assert.Equal(t, "l1", proc.startingLink(ctx))
+	status := proc.Status()
+	assert.Equal(t, models.StateBackingOff, status.State)
+	assert.Equal(t, "failed to call api", status.LastError)
+}
+
+func Test_ControllerHealth(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+
+	tests := []struct {
+		name            string
+		tokenValid      bool
+		statuses        []models.ProcessorStatus
+		expectedHealthy bool
+		expectedReady   bool
+	}{
+		{
+			name:       "happy",
+			tokenValid: true,
+			statuses: []models.ProcessorStatus{
+				{Subreddit: "a", State: models.StateRunning, LastPoll: time.Now()},
+				{Subreddit: "b", State: models.StateRunning, LastPoll: time.Now()},
+			},
+			expectedHealthy: true,
+			expectedReady:   true,
+		},
+		{
+			name:       "invalid token",
+			tokenValid: false,
+			statuses: []models.ProcessorStatus{
+				{Subreddit: "a", State: models.StateRunning, LastPoll: time.Now()},
+			},
+			expectedHealthy: true,
+			expectedReady:   false,
+		},
+		{
+			name:       "initializing",
+			tokenValid: true,
+			statuses: []models.ProcessorStatus{
+				{Subreddit: "a", State: models.StateInitializing, StateSince: time.Now()},
+			},
+			expectedHealthy: true,
+			expectedReady:   false,
+		},
+		{
+			name:       "backing off within threshold",
+			tokenValid: true,
+			statuses: []models.ProcessorStatus{
+				{Subreddit: "a", State: models.StateBackingOff, LastPoll: time.Now().Add(-time.Minute)},
+			},
+			expectedHealthy: true,
+			expectedReady:   false,
+		},
+		{
+			name:       "backing off beyond threshold",
+			tokenValid: true,
+			statuses: []models.ProcessorStatus{
+				{Subreddit: "a", State: models.StateRunning, LastPoll: time.Now()},
+				{Subreddit: "b", State: models.StateBackingOff, LastPoll: time.Now().Add(-time.Hour)},
+			},
+			expectedHealthy: false,
+			expectedReady:   false,
+		},
+		{
+			name:       "failed",
+			tokenValid: true,
+			statuses: []models.ProcessorStatus{
+				{Subreddit: "a", State: models.StateFailed, StateSince: time.Now()},
+			},
+			expectedHealthy: false,
+			expectedReady:   false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := mocks.NewClient(t)
+			client.On("TokenValid").Once().Return(tc.tokenValid)
+			ctrl := &controller{
+				logger:     logger,
+				client:     client,
+				threshold:  defaultHealthThreshold,
+				started:    true,
+				processors: map[string]Processor{},
+			}
+			for _, status := range tc.statuses {
+				p := mocks.NewProcessor(t)
+				p.On("Status").Once().Return(status)
+				ctrl.processors[status.Subreddit] = p
+			}
+
+			health := ctrl.Health(ctx)
+
+			assert.Equal(t, tc.expectedHealthy, health.Healthy)
+			assert.Equal(t, tc.expectedReady, health.Ready)
+			assert.Equal(t, tc.tokenValid, health.TokenValid)
+			assert.Len(t, health.Processors, len(tc.statuses))
+		})
+	}
+}
+
+func Test_ControllerMetrics(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	proc := mocks.NewProcessor(t)
+	proc.On("Status").Return(models.ProcessorStatus{Subreddit: "test", Links: 3, Users: 2, MemoryBytes: 1024})
+	ctrl := &controller{
+		logger:     logger,
+		processors: map[string]Processor{"test": proc},
+	}
+	assert.NoError(t, ctrl.registerMetrics(meter))
+
+	collected := metricdata.ResourceMetrics{}
+	assert.NoError(t, reader.Collect(ctx, &collected))
+	gauges := map[string]int64{}
+	for _, scope := range collected.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			for _, point := range m.Data.(metricdata.Gauge[int64]).DataPoints {
+				subreddit, _ := point.Attributes.Value(tracing.SubredditKey)
+				assert.Equal(t, "test", subreddit.AsString())
+				gauges[m.Name] = point.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{
+		"reddit.processor.links":  3,
+		"reddit.processor.users":  2,
+		"reddit.processor.memory": 1024,
+	}, gauges)
+}
+
+func Test_ProcessorBackfill(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	after := "l2"
+	old := models.Link{
+		Data: models.LinkData{
+			Name:       "old",
+			CreatedUTC: float64(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		},
+	}
+	recent := models.Link{
+		Data: models.LinkData{
+			Name:       "recent",
+			CreatedUTC: float64(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		},
+	}
+
+	tests := []struct {
+		name          string
+		until         string
+		responses     []models.Listing
+		errs          []error
+		expectedState models.BackfillState
+		expectedPages int
+		expectedLinks int
+	}{
+		{
+			name:  "pages until exhausted",
+			until: "",
+			responses: []models.Listing{
+				{Data: models.ListingData{After: &after, Children: []models.Link{l1, l2}}},
+				{Data: models.ListingData{Children: []models.Link{l3}}},
+			},
+			errs:          []error{nil, nil},
+			expectedState: models.BackfillComplete,
+			expectedPages: 2,
+			expectedLinks: 3,
+		},
+		{
+			name:  "stops at date bound",
+			until: "2024-01-01",
+			responses: []models.Listing{
+				{Data: models.ListingData{After: &after, Children: []models.Link{recent, old}}},
+			},
+			errs:          []error{nil},
+			expectedState: models.BackfillComplete,
+			expectedPages: 1,
+			expectedLinks: 1,
+		},
+		{
+			name:  "gives up after retries",
+			until: "",
+			responses: []models.Listing{
+				{}, {}, {}, {}, {}, {},
+			},
+			errs: []error{
+				errors.New("failed to call api"),
+				errors.New("failed to call api"),
+				errors.New("failed to call api"),
+				errors.New("failed to call api"),
+				errors.New("failed to call api"),
+				errors.New("failed to call api"),
+			},
+			expectedState: models.BackfillFailed,
+			expectedPages: 0,
+			expectedLinks: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := mocks.NewClient(t)
+			config := targetConfig{
+				Name: "test",
+				Backfill: backfillConfig{
+					Enabled:  true,
+					Until:    tc.until,
+					Interval: time.Millisecond,
+				},
+			}
+			proc := NewProcessor(logger, client, nil, nil, nil, nil, config).(*processor)
+			for i := range tc.responses {
+				client.On("GetLinkListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(tc.responses[i], tc.errs[i])
+			}
+
+			proc.startBackfill(ctx)
+
+			progress := proc.Backfill()
+			assert.Len(t, progress, 1)
+			assert.Equal(t, tc.expectedState, progress[0].State)
+			assert.Equal(t, tc.expectedPages, progress[0].Pages)
+			assert.Equal(t, tc.expectedLinks, progress[0].Links)
+			assert.Len(t, proc.links, tc.expectedLinks)
+		})
+	}
+}
+
+func Test_ProcessorComments(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	config := targetConfig{
+		Name: "test",
+		Comments: commentsConfig{
+			Enabled: true,
+		},
+	}
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, config).(*processor)
+
+	c1 := models.Comment{Data: models.CommentData{Name: "c1", Author: "u1", AuthorFullname: "t2_u1", Score: 10}}
+	c2 := models.Comment{Data: models.CommentData{Name: "c2", Author: "u2", AuthorFullname: "t2_u2", Score: 2}}
+	c3 := models.Comment{Data: models.CommentData{Name: "c3", Author: "u2", AuthorFullname: "t2_u2", Score: 3}}
+
+	client.On("GetCommentListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(models.CommentListing{
+		Data: models.CommentListingData{
+			Children: []models.Comment{c3, c2, c1},
+		},
+	}, nil)
+	client.On("GetCommentListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(models.CommentListing{}, errors.New("failed to call api"))
+
+	newest, err := proc.processComments(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "c3", newest)
+
+	newest, err = proc.processComments(ctx, newest)
+	assert.Error(t, err)
+	assert.Equal(t, "", newest)
+
+	stats, err := proc.Stats(ctx, models.StatsQuery{Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c1", "c3", "c2"}, []string{stats.Comments[0].Name, stats.Comments[1].Name, stats.Comments[2].Name})
+	assert.Equal(t, []models.CommenterStats{
+		{Name: "u2", CommentCount: 2, TotalScore: 5},
+		{Name: "u1", CommentCount: 1, TotalScore: 10},
+	}, stats.Commenters)
+	assert.Equal(t, []models.CommenterStats{
+		{Name: "u1", CommentCount: 1, TotalScore: 10},
+		{Name: "u2", CommentCount: 2, TotalScore: 5},
+	}, stats.CommentersByScore)
+}
+
+func Test_ProcessorRefreshComments(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	config := targetConfig{
+		Name: "test",
+		Comments: commentsConfig{
+			Enabled: true,
+			MaxAge:  time.Hour,
+		},
+	}
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, config).(*processor)
+	now := time.Now()
+
+	comment := func(name string, age time.Duration, score int) models.Comment {
+		return models.Comment{Data: models.CommentData{Name: name, Score: score, CreatedUTC: float64(now.Add(-age).Unix())}}
+	}
+	proc.comments["t1_c1"] = comment("t1_c1", time.Minute, 1)
+	proc.comments["t1_c2"] = comment("t1_c2", 2*time.Hour, 1)
+	proc.comments["t1_c3"] = comment("t1_c3", time.Minute, 1)
+
+	// only young comments are refreshed and comments evicted during the lookup aren't added back
+	client.On("GetCommentListing", mock.Anything, "https://oauth.reddit.com/api/info", mock.MatchedBy(func(values url.Values) bool {
+		ids := strings.Split(values.Get("id"), ",")
+		slices.Sort(ids)
+		return slices.Equal(ids, []string{"t1_c1", "t1_c3"})
+	})).Once().Run(func(mock.Arguments) {
+		delete(proc.comments, "t1_c3")
+	}).Return(models.CommentListing{
+		Data: models.CommentListingData{
+			Children: []models.Comment{comment("t1_c1", time.Minute, 25), comment("t1_c3", time.Minute, 7)},
+		},
+	}, nil)
+	assert.NoError(t, proc.refreshComments(ctx, now))
+	assert.Equal(t, 25, proc.comments["t1_c1"].Data.Score)
+	assert.Equal(t, 1, proc.comments["t1_c2"].Data.Score)
+	assert.NotContains(t, proc.comments, "t1_c3")
+
+	client.On("GetCommentListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(models.CommentListing{}, errors.New("failed to call api"))
+	assert.Error(t, proc.refreshComments(ctx, now))
+}
+
+func Test_ProcessorRankings(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	config := targetConfig{
+		Name: "test",
+		Listings: listingsConfig{
+			Sources: []string{"rising", "hot", "top?t=day", "top?t=week"},
+		},
+	}
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, config).(*processor)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	samples := []struct {
+		source   string
+		url      string
+		children []models.Link
+		at       time.Time
+	}{
+		{"rising", "https://oauth.reddit.com/r/test/rising", []models.Link{l1, l2}, start},
+		{"hot", "https://oauth.reddit.com/r/test/hot", []models.Link{l3}, start},
+		{"rising", "https://oauth.reddit.com/r/test/rising", []models.Link{l2}, start.Add(time.Minute)},
+		{"hot", "https://oauth.reddit.com/r/test/hot", []models.Link{l3, l1}, start.Add(time.Minute)},
+		{"hot", "https://oauth.reddit.com/r/test/hot", []models.Link{l1, l3}, start.Add(2 * time.Minute)},
+		{"hot", "https://oauth.reddit.com/r/test/hot", []models.Link{l1}, start.Add(3 * time.Minute)},
+		{"hot", "https://oauth.reddit.com/r/test/hot", []models.Link{l1, l3}, start.Add(5 * time.Minute)},
+		{"top?t=day", "https://oauth.reddit.com/r/test/top", []models.Link{l1}, start.Add(2 * time.Minute)},
+		{"top?t=week", "https://oauth.reddit.com/r/test/top", []models.Link{l2}, start.Add(2 * time.Minute)},
+	}
+	for _, s := range samples {
+		client.On("GetLinkListing", mock.Anything, s.url, mock.Anything).Once().Return(models.Listing{
+			Data: models.ListingData{Children: s.children},
+		}, nil)
+		err := proc.sampleListing(ctx, s.source, s.at)
+		assert.NoError(t, err)
+	}
+
+	rankings := proc.Rankings(ctx, "", 5)
+
+	listings := []string{}
+	for _, l := range rankings.Listings {
+		listings = append(listings, l.Listing)
+	}
+	assert.Equal(t, []string{"hot", "rising", "top?t=day", "top?t=week"}, listings)
+	hot := rankings.Listings[0].Posts
+	assert.Equal(t, "l1", hot[0].Name)
+	assert.Equal(t, int64(240), hot[0].DurationSeconds)
+	assert.Equal(t, 1, hot[0].BestRank)
+	assert.Equal(t, 1, hot[0].LatestRank)
+	// l3 dropped off the front page between the third and fifth minute
+	assert.Equal(t, "l3", hot[1].Name)
+	assert.Equal(t, int64(120), hot[1].DurationSeconds)
+	assert.Equal(t, 1, hot[1].BestRank)
+	assert.Equal(t, 2, hot[1].LatestRank)
+	assert.Equal(t, []models.RankPoint{{Time: start, Rank: 1}, {Time: start.Add(2 * time.Minute), Rank: 2}}, hot[1].Ranks)
+	assert.Equal(t, "l1", rankings.Listings[2].Posts[0].Name)
+	assert.Equal(t, "l2", rankings.Listings[3].Posts[0].Name)
+
+	assert.Equal(t, []models.RisingToHotStats{
+		{Name: "l1", RisingAt: start, HotAt: start.Add(time.Minute)},
+	}, rankings.RisingToHot)
+
+	// none of the links were posted to another member of a multireddit
+	rankings = proc.Rankings(ctx, "other", 5)
+	assert.Empty(t, rankings.Listings)
+	assert.Empty(t, rankings.RisingToHot)
+}
+
+func Test_ProcessorHistory(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, targetConfig{Name: "test"}).(*processor)
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	link := func(ups int) models.Link {
+		return models.Link{Data: models.LinkData{Name: "l1", Subreddit: "test", Ups: ups}}
+	}
+	proc.links["l1"] = link(0)
+
+	// two days of refreshes every minute
+	start := now.Add(-48 * time.Hour)
+	for i := 0; i <= 48*60; i++ {
+		proc.recordScore(link(i), start.Add(time.Duration(i)*time.Minute))
+	}
+	// refreshes closer than the minimum spacing replace the latest point
+	proc.recordScore(link(10000), now.Add(time.Second))
+
+	history, ok := proc.History(ctx, "l1")
+	assert.True(t, ok)
+	assert.Equal(t, "test", history.Subreddit)
+
+	points := history.Points
+	assert.Equal(t, 10000, points[len(points)-1].Ups)
+	// 24 hourly points, 23 hours of 5 minute points and the last hour at full resolution
+	assert.InDelta(t, 24+23*12+60, len(points), 2)
+	for i := 1; i < len(points); i++ {
+		assert.True(t, points[i].Time.After(points[i-1].Time))
+	}
+
+	_, ok = proc.History(ctx, "missing")
+	assert.False(t, ok)
+}
+
+func Test_TrendingScore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	created := func(age time.Duration) float64 {
+		return float64(now.Add(-age).Unix())
+	}
+	points := func(ups ...int) []models.ScorePoint {
+		result := []models.ScorePoint{}
+		for i, u := range ups {
+			result = append(result, models.ScorePoint{
+				Time: now.Add(time.Duration(i-len(ups)+1) * 10 * time.Minute),
+				Ups:  u,
+			})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		link     models.LinkData
+		points   []models.ScorePoint
+		expected float64
+	}{
+		{
+			name:     "no history uses average since creation",
+			link:     models.LinkData{Ups: 60, CreatedUTC: created(time.Hour)},
+			expected: 1 * math.Pow(0.5, 1.0/6),
+		},
+		{
+			name:     "history uses recent velocity",
+			link:     models.LinkData{Ups: 600, CreatedUTC: created(time.Hour)},
+			points:   points(0, 100, 200, 400, 600),
+			expected: 500.0 / 30 * math.Pow(0.5, 1.0/6),
+		},
+		{
+			name:     "brand new posts are measured over at least a minute",
+			link:     models.LinkData{Ups: 10, CreatedUTC: created(time.Second)},
+			expected: 10 * math.Pow(0.5, 1.0/60/6),
+		},
+		{
+			name:     "losing votes is not trending",
+			link:     models.LinkData{Ups: 10, CreatedUTC: created(time.Hour)},
+			points:   points(20, 10),
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, trendingScore(tc.link, tc.points, now), 1e-9)
+		})
+	}
+}
+
+func Test_ProcessorPrediction(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, targetConfig{Name: "test"}).(*processor)
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	link := func(name string, ups int, comments int) models.Link {
+		return models.Link{Data: models.LinkData{
+			Name:        name,
+			Ups:         ups,
+			NumComments: comments,
+			CreatedUTC:  float64(created.Unix()),
+		}}
+	}
+
+	// not enough history yet
+	_, err := proc.Backtest(ctx, "")
+	assert.Error(t, err)
+
+	// links that end up with ~20x their score after 30 minutes
+	for i := range 40 {
+		name := fmt.Sprintf("l%d", i)
+		early := 10 + 5*i
+		proc.links[name] = link(name, early, i)
+		proc.recordScore(link(name, early/2, i/2), created.Add(10*time.Minute))
+		proc.recordScore(link(name, early, i), created.Add(30*time.Minute))
+		proc.recordScore(link(name, 20*early, 4*i), created.Add(24*time.Hour))
+		proc.recordScore(link(name, 21*early, 4*i), created.Add(25*time.Hour))
+	}
+	// a new link observed early on
+	proc.links["new"] = link("new", 100, 18)
+	proc.recordScore(link("new", 100, 18), created.Add(30*time.Minute))
+
+	// the model isn't fit until the next poll
+	stats, err := proc.Stats(ctx, models.StatsQuery{Limit: 100})
+	assert.NoError(t, err)
+	for _, l := range stats.Posts {
+		assert.Nil(t, l.Prediction)
+	}
+
+	proc.fitPredictor()
+	stats, err = proc.Stats(ctx, models.StatsQuery{Limit: 100})
+	assert.NoError(t, err)
+	for _, l := range stats.Posts {
+		if l.Name != "new" {
+			assert.Nil(t, l.Prediction, "links past the horizon aren't predicted")
+			continue
+		}
+		assert.NotNil(t, l.Prediction)
+		assert.InEpsilon(t, 2000, l.Prediction.Score, 0.1)
+		assert.Less(t, l.Prediction.Lower, l.Prediction.Score)
+		assert.Greater(t, l.Prediction.Upper, l.Prediction.Score)
+		assert.Equal(t, predictionConfidence, l.Prediction.Confidence)
+	}
+
+	backtest, err := proc.Backtest(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 40, backtest.Samples)
+	assert.Greater(t, backtest.LogRSquared, 0.95)
+	assert.Less(t, backtest.MedianAbsolutePercentError, 0.1)
+}
+
+func Test_Enricher(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	e := NewEnricher(logger, client, enrichmentConfig{Enabled: true, TTL: time.Hour}).(*enricher)
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	client.On("GetUserAbout", mock.Anything, "https://oauth.reddit.com/user/u1/about", mock.Anything).Once().Return(models.UserAbout{
+		Data: models.UserAboutData{
+			Name:         "u1",
+			CreatedUTC:   float64(created.Unix()),
+			LinkKarma:    10,
+			CommentKarma: 20,
+			Verified:     true,
+		},
+	}, nil)
+	client.On("GetUserAbout", mock.Anything, "https://oauth.reddit.com/user/gone/about", mock.Anything).Once().Return(models.UserAbout{}, clientpkg.ErrNotFound)
+	client.On("GetUserAbout", mock.Anything, "https://oauth.reddit.com/user/u2/about", mock.Anything).Once().Return(models.UserAbout{}, errors.New("failed to call api"))
+
+	// tracking queues each author once
+	e.Track("u1")
+	e.Track("u1")
+	e.Track("[deleted]")
+	assert.Len(t, e.queue, 1)
+	assert.Nil(t, e.Account("u1"))
+
+	assert.NoError(t, e.enrich(ctx, "u1"))
+	assert.NoError(t, e.enrich(ctx, "gone"))
+	assert.Error(t, e.enrich(ctx, "u2"))
+
+	account := e.Account("u1")
+	assert.NotNil(t, account)
+	assert.Equal(t, created, account.Created.UTC())
+	assert.Equal(t, 10, account.LinkKarma)
+	assert.Equal(t, 20, account.CommentKarma)
+	assert.True(t, account.Verified)
+	assert.True(t, e.Account("gone").Deleted)
+	assert.Nil(t, e.Account("u2"))
+
+	// fresh accounts aren't queued again but stale ones are
+	<-e.queue
+	delete(e.queued, "u1")
+	e.Track("u1")
+	assert.Len(t, e.queue, 0)
+	account.FetchedAt = time.Now().Add(-2 * time.Hour)
+	e.Track("u1")
+	assert.Len(t, e.queue, 1)
+}
+
+func Test_ControllerUser(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	enricher := mocks.NewEnricher(t)
+	account := &models.Account{LinkKarma: 1}
+	ctrl := &controller{
+		logger:     logger,
+		enricher:   enricher,
+		processors: map[string]Processor{},
+	}
+	for name, count := range map[string]int{"a": 1, "b": 3, "c": 0} {
+		p := mocks.NewProcessor(t)
+		p.On("User", ctx, "u1").Return(models.UserStats{Name: "u1", PostCount: count}, count > 0)
+		ctrl.processors[name] = p
+	}
+	enricher.On("Account", "u1").Once().Return(account)
+
+	profile, err := ctrl.User(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserProfile{
+		Name:    "u1",
+		Account: account,
+		Subreddits: []models.UserSubredditStats{
+			{Subreddit: "b", PostCount: 3},
+			{Subreddit: "a", PostCount: 1},
+		},
+	}, profile)
+
+	for _, p := range ctrl.processors {
+		p.(*mocks.Processor).On("User", ctx, "missing").Return(models.UserStats{}, false)
+	}
+	_, err = ctrl.User(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_ControllerStatsMultireddit(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	multi := mocks.NewProcessor(t)
+	ctrl := &controller{
+		logger:     logger,
+		processors: map[string]Processor{"golang+rust": multi},
+	}
+
+	multi.On("Stats", mock.Anything, models.StatsQuery{Limit: 5, Subreddit: "rust"}).Once().Return(models.Stats{}, nil)
+	_, err := ctrl.Stats(ctx, "rust", models.StatsQuery{Limit: 5})
+	assert.NoError(t, err)
+
+	multi.On("Terms", mock.Anything, models.TermsQuery{Limit: 5, Subreddit: "rust"}).Once().Return(models.Terms{})
+	_, err = ctrl.Terms(ctx, "rust", models.TermsQuery{Limit: 5})
+	assert.NoError(t, err)
+
+	multi.On("Reposts", mock.Anything, "rust", 5).Once().Return([]models.Repost{})
+	_, err = ctrl.Reposts(ctx, "rust", 5)
+	assert.NoError(t, err)
+
+	multi.On("Removed", mock.Anything, "", 5).Once().Return([]models.Removal{})
+	_, err = ctrl.Removed(ctx, "golang+rust", 5)
+	assert.NoError(t, err)
+
+	multi.On("Trending", mock.Anything, "rust", 5).Once().Return([]models.LinkStats{})
+	_, err = ctrl.Trending(ctx, "rust", 5)
+	assert.NoError(t, err)
+
+	multi.On("Rankings", mock.Anything, "rust", 5).Once().Return(models.Rankings{})
+	_, err = ctrl.Rankings(ctx, "rust", 5)
+	assert.NoError(t, err)
+
+	multi.On("Backtest", mock.Anything, "rust").Once().Return(models.Backtest{}, nil)
+	_, err = ctrl.Backtest(ctx, "rust")
+	assert.NoError(t, err)
+
+	multi.On("Backfill").Once().Return([]models.BackfillProgress{})
+	_, err = ctrl.Backfill(ctx, "rust")
+	assert.NoError(t, err)
+
+	multi.On("Anomaly", mock.Anything, "rust").Once().Return(models.Anomaly{Subreddit: "rust"}, true)
+	anomalies, err := ctrl.Anomalies(ctx, "rust")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Anomaly{{Subreddit: "rust"}}, anomalies)
+
+	_, err = ctrl.Stats(ctx, "python", models.StatsQuery{Limit: 5})
+	assert.Error(t, err)
+}
+
+func Test_Tokenize(t *testing.T) {
+	tests := []struct {
+		name            string
+		title           string
+		expectedTerms   []string
+		expectedBigrams []string
+	}{
+		{
+			name:            "stopwords and punctuation",
+			title:           "The new Go release is out!",
+			expectedTerms:   []string{"new", "go", "release"},
+			expectedBigrams: []string{"new go", "go release"},
+		},
+		{
+			name:            "normalisation",
+			title:           "Reddit's API: reddit API changes (2024)",
+			expectedTerms:   []string{"reddit", "api", "changes"},
+			expectedBigrams: []string{"reddit api", "api reddit", "api changes"},
+		},
+		{
+			name:            "nothing left",
+			title:           "Is this it? 100%",
+			expectedTerms:   []string{},
+			expectedBigrams: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			terms, bigrams := tokenize(tc.title, newStopwords(nil))
+			assert.Equal(t, tc.expectedTerms, terms)
+			assert.Equal(t, tc.expectedBigrams, bigrams)
+		})
+	}
+}
+
+func Test_ProcessorTerms(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	config := targetConfig{Name: "test", Terms: termsConfig{Stopwords: []string{"Weekly"}}}
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, config).(*processor)
+	now := time.Now()
+
+	for i, l := range []struct {
+		title string
+		age   time.Duration
+	}{
+		{title: "Weekly Go release thread", age: 30 * time.Hour},
+		{title: "Go release notes", age: 40 * time.Hour},
+		{title: "Rust release notes", age: 2 * time.Hour},
+		{title: "Rust async runtimes", age: 3 * time.Hour},
+		{title: "Go generics", age: 4 * time.Hour},
+		{title: "Ancient history", age: 72 * time.Hour},
+	} {
+		proc.recordTerms(models.Link{Data: models.LinkData{
+			Name:       fmt.Sprintf("l%d", i),
+			Title:      l.title,
+			CreatedUTC: float64(now.Add(-l.age).Unix()),
+		}}, now)
+	}
+
+	terms := proc.Terms(ctx, models.TermsQuery{Window: 24 * time.Hour, Limit: 2})
+	assert.Equal(t, 3, terms.Posts)
+	assert.Equal(t, 2, terms.PreviousPosts)
+	assert.Equal(t, []models.TermStats{
+		{Term: "rust", Count: 2, PreviousCount: 0, Growth: 3},
+		{Term: "async", Count: 1, PreviousCount: 0, Growth: 2},
+	}, terms.Terms)
+	assert.Equal(t, []models.TermStats{
+		{Term: "async runtimes", Count: 1, PreviousCount: 0, Growth: 2},
+		{Term: "go generics", Count: 1, PreviousCount: 0, Growth: 2},
+	}, terms.Bigrams)
+	assert.Equal(t, []models.TermStats{
+		{Term: "rust", Count: 2, PreviousCount: 0, Growth: 3},
+	}, terms.Emerging)
+}
+
+func Test_ProcessorReposts(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	index := newRepostIndex()
+	pics := NewProcessor(logger, client, nil, nil, index, nil, targetConfig{Name: "pics"}).(*processor)
+	funny := NewProcessor(logger, client, nil, nil, index, nil, targetConfig{Name: "funny"}).(*processor)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	link := func(name, subreddit, title, url string, age time.Duration) models.Link {
+		return models.Link{Data: models.LinkData{
+			Name:           name,
+			Subreddit:      subreddit,
+			Title:          title,
+			URL:            url,
+			Author:         "u1",
+			AuthorFullname: "u1",
+			CreatedUTC:     float64(start.Add(age).Unix()),
+		}}
+	}
+	for _, l := range []struct {
+		proc *processor
+		link models.Link
+	}{
+		{proc: pics, link: link("l1", "pics", "My cat sleeping in a cardboard box", "https://i.redd.it/cat.jpg", 0)},
+		{proc: funny, link: link("l2", "funny", "Totally unrelated", "https://www.i.redd.it/cat.jpg/", time.Hour)},
+		{proc: pics, link: link("l3", "pics", "My cat sleeping in a cardboard box!", "https://i.redd.it/other.jpg", 2*time.Hour)},
+		{proc: pics, link: link("l4", "pics", "A dog chasing its tail at the park", "https://i.redd.it/dog.jpg", 3*time.Hour)},
+	} {
+		l.proc.processLink(ctx, l.link)
+		l.proc.processUser(ctx, l.link)
+	}
+
+	assert.Equal(t, []models.Repost{{
+		Name:              "l2",
+		Subreddit:         "funny",
+		Title:             "Totally unrelated",
+		Author:            "u1",
+		Created:           start.Add(time.Hour).Local(),
+		Original:          "l1",
+		OriginalSubreddit: "pics",
+		OriginalTitle:     "My cat sleeping in a cardboard box",
+		Similarity:        1,
+		SameURL:           true,
+	}}, funny.Reposts(ctx, "", 5))
+
+	reposts := pics.Reposts(ctx, "", 5)
+	assert.Len(t, reposts, 1)
+	assert.Equal(t, "l3", reposts[0].Name)
+	assert.Equal(t, "l1", reposts[0].Original)
+	assert.False(t, reposts[0].SameURL)
+	assert.Equal(t, 1.0, reposts[0].Similarity)
+
+	user, ok := pics.User(ctx, "u1")
+	assert.True(t, ok)
+	assert.Equal(t, 3, user.PostCount)
+	assert.Equal(t, 1, user.RepostCount)
+}
+
+func Test_RepostIndexRemove(t *testing.T) {
+	index := newRepostIndex()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := func(name string, age time.Duration) indexedPost {
+		return indexedPost{name: name, subreddit: "pics", created: start.Add(age)}
+	}
+
+	// the same post is tracked by both a subreddit and a multireddit that includes it
+	_, ok := index.add("pics", post("l1", 0), "i.redd.it/cat.jpg")
+	assert.False(t, ok)
+	_, ok = index.add("pics+funny", post("l1", 0), "i.redd.it/cat.jpg")
+	assert.False(t, ok)
+	assert.Len(t, index.posts, 1)
+
+	// evicting it from one target leaves it indexed for the other
+	index.remove("pics", map[string]bool{"l1": true})
+	repost, ok := index.add("funny", post("l2", time.Hour), "i.redd.it/cat.jpg")
+	assert.True(t, ok)
+	assert.Equal(t, "l1", repost.Original)
+
+	index.remove("pics+funny", map[string]bool{"l1": true})
+	assert.Equal(t, []string{"l2"}, slices.Sorted(maps.Keys(index.posts)))
+	assert.Empty(t, index.urls)
+}
+
+func Test_ControllerOverlap(t *testing.T) {
+	ctx := context.Background()
+	ctrl := &controller{authors: newAuthorIndex()}
+	for i, post := range []struct {
+		author    string
+		subreddit string
+	}{
+		{author: "Alice", subreddit: "golang"},
+		{author: "alice", subreddit: "rust"},
+		{author: "alice", subreddit: "rust"},
+		{author: "bob", subreddit: "golang"},
+		{author: "bob", subreddit: "rust"},
+		{author: "carol", subreddit: "golang"},
+		{author: "carol", subreddit: "python"},
+		{author: "dave", subreddit: "python"},
+		{author: models.DeletedAuthor, subreddit: "rust"},
+	} {
+		ctrl.authors.add("test", models.LinkData{
+			Name:      fmt.Sprintf("l%d", i),
+			Author:    post.author,
+			Subreddit: post.subreddit,
+		})
+	}
+	// posts tracked by more than one target are only counted once
+	ctrl.authors.add("golang+rust", models.LinkData{Name: "l1", Author: "alice", Subreddit: "rust"})
+
+	assert.Equal(t, []models.SubredditOverlap{
+		{Subreddit: "golang", Other: "rust", Authors: 3, OtherAuthors: 2, SharedAuthors: 2, Similarity: 2.0 / 3},
+		{Subreddit: "golang", Other: "python", Authors: 3, OtherAuthors: 2, SharedAuthors: 1, Similarity: 1.0 / 4},
+	}, ctrl.Overlap(ctx, "", 5))
+	assert.Equal(t, []models.SubredditOverlap{
+		{Subreddit: "python", Other: "golang", Authors: 2, OtherAuthors: 3, SharedAuthors: 1, Similarity: 1.0 / 4},
+	}, ctrl.Overlap(ctx, "Python", 5))
+
+	assert.Equal(t, []models.CrossActiveAuthor{
+		{Name: "Alice", PostCount: 3, Subreddits: []models.UserSubredditStats{
+			{Subreddit: "rust", PostCount: 2},
+			{Subreddit: "golang", PostCount: 1},
+		}},
+	}, ctrl.CrossActive(ctx, 1))
+	assert.Equal(t, 2, ctrl.authors.subreddits("CAROL"))
+
+	// posts stay indexed until every target tracking them has released them
+	ctrl.authors.remove("test", map[string]bool{"l1": true, "l2": true})
+	assert.Equal(t, 2, ctrl.authors.subreddits("alice"))
+	ctrl.authors.remove("golang+rust", map[string]bool{"l1": true})
+	assert.Equal(t, 1, ctrl.authors.subreddits("alice"))
+}
+
+func Test_ProcessorAuthorIndex(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	authors := newAuthorIndex()
+	sub := NewProcessor(logger, client, nil, nil, nil, authors, targetConfig{Name: "golang"}).(*processor)
+	followed := NewProcessor(logger, client, nil, nil, nil, authors, targetConfig{Name: "alice", kind: targetUser}).(*processor)
+
+	link := func(name, subreddit string) models.Link {
+		return models.Link{Data: models.LinkData{Name: name, Author: "alice", AuthorFullname: "t2_alice", Subreddit: subreddit}}
+	}
+	sub.processLink(ctx, link("l1", "golang"))
+	sub.processLink(ctx, link("l1", "golang"))
+	followed.processLink(ctx, link("l1", "golang"))
+	followed.processLink(ctx, link("l2", "rust"))
+
+	// followed users don't sample the communities they post to
+	assert.Equal(t, 1, authors.subreddits("alice"))
+	assert.Len(t, authors.posts, 1)
+	assert.Equal(t, []models.CrossActiveAuthor{}, authors.crossActive())
+}
+
+func Test_Sentiment(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected float64
+	}{
+		{name: "neutral", text: "Posted this from my phone", expected: 0},
+		{name: "positive", text: "What a great day", expected: 3 / math.Sqrt(9+15)},
+		{name: "negative", text: "This update is terrible", expected: -3 / math.Sqrt(9+15)},
+		{name: "negated", text: "It's not bad at all", expected: 3 / math.Sqrt(9+15)},
+		{name: "contraction", text: "I don’t love it", expected: -3 / math.Sqrt(9+15)},
+		{name: "intensified", text: "Really good", expected: 4.5 / math.Sqrt(4.5*4.5+15)},
+		{name: "mixed", text: "Good idea, awful execution", expected: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, sentiment(tc.text), 1e-9)
+		})
+	}
+}
+
+func Test_SentimentStats(t *testing.T) {
+	now := time.Date(2024, 1, 8, 12, 30, 0, 0, time.UTC)
+	posts := []models.LinkStats{
+		{Name: "l1", Sentiment: 0.5, Created: now.Add(-time.Minute)},
+		{Name: "l2", Sentiment: -0.5, Created: now.Add(-2 * time.Minute)},
+		{Name: "l3", Sentiment: 0.9, Created: now.Add(-2 * time.Hour)},
+		{Name: "l4", Sentiment: 0, Created: now.Add(-30 * 24 * time.Hour)},
+	}
+	comments := []models.CommentStats{
+		{Name: "c1", Sentiment: -0.2, Created: now.Add(-time.Minute)},
+	}
+
+	stats := sentimentStats(posts, comments, 1, now)
+	assert.InDelta(t, 0.225, stats.Average, 1e-9)
+	assert.InDelta(t, -0.2, stats.CommentAverage, 1e-9)
+	assert.Equal(t, []models.LinkStats{posts[2]}, stats.Positive)
+	assert.Equal(t, []models.LinkStats{posts[1]}, stats.Negative)
+	assert.Equal(t, []models.SentimentPoint{
+		{Time: now.Add(-150 * time.Minute), Posts: 1, Average: 0.9},
+		{Time: now.Add(-30 * time.Minute), Posts: 2, Average: 0, Comments: 1, CommentAverage: -0.2},
+	}, stats.Points)
+}
+
+func Test_ProcessorSuspicion(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	enricher := mocks.NewEnricher(t)
+	proc := NewProcessor(logger, client, enricher, nil, nil, nil, targetConfig{Name: "all"}).(*processor)
+	start := time.Now().Add(-24 * time.Hour)
+	enricher.On("Track", mock.Anything)
+	enricher.On("Account", "bot").Return(&models.Account{Created: start.Add(-24 * time.Hour)})
+	enricher.On("Account", "human").Return(&models.Account{Created: start.Add(-5 * 365 * 24 * time.Hour)})
+
+	for i := 0; i < 5; i++ {
+		bot := models.Link{Data: models.LinkData{
+			Name:           fmt.Sprintf("b%d", i),
+			Subreddit:      fmt.Sprintf("sub%d", i),
+			Title:          fmt.Sprintf("Cheap deals %d, click now", i),
+			Author:         "bot",
+			AuthorFullname: "bot",
+			Domain:         "spam.example",
+			CreatedUTC:     float64(start.Add(time.Duration(i) * time.Minute).Unix()),
+		}}
+		human := models.Link{Data: models.LinkData{
+			Name:           fmt.Sprintf("h%d", i),
+			Subreddit:      "pics",
+			Title:          []string{"My dog", "A sunset", "Homemade bread", "Old photo", "New desk"}[i],
+			Author:         "human",
+			AuthorFullname: "human",
+			Domain:         []string{"i.redd.it", "imgur.com", "i.redd.it", "flickr.com", "imgur.com"}[i],
+			CreatedUTC:     float64(start.Add(time.Duration(i) * 5 * time.Hour).Unix()),
+		}}
+		for _, l := range []models.Link{bot, human} {
+			proc.processLink(ctx, l)
+			proc.processUser(ctx, l)
+		}
+	}
+
+	bot, ok := proc.User(ctx, "bot")
+	assert.True(t, ok)
+	assert.InDelta(t, 1, bot.Suspicion, 1e-9)
+	human, ok := proc.User(ctx, "human")
+	assert.True(t, ok)
+	assert.InDelta(t, 0, human.Suspicion, 1e-9)
+
+	maxSuspicion := 0.5
+	stats, err := proc.Stats(ctx, models.StatsQuery{Limit: 10, MaxSuspicion: &maxSuspicion})
+	assert.NoError(t, err)
+	assert.Len(t, stats.Posts, 5)
+	for _, l := range stats.Posts {
+		assert.Equal(t, "human", l.Author)
+	}
+	assert.Len(t, stats.Users, 1)
+	assert.Equal(t, "human", stats.Users[0].Name)
+
+	// zero is a valid threshold that only leaves authors without any suspicion
+	maxSuspicion = 0
+	stats, err = proc.Stats(ctx, models.StatsQuery{Limit: 10, MaxSuspicion: &maxSuspicion})
+	assert.NoError(t, err)
+	assert.Len(t, stats.Users, 1)
+	assert.Equal(t, "human", stats.Users[0].Name)
+}
+
+func Test_ProcessorRemovals(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, targetConfig{Name: "test"}).(*processor)
+	now := time.Now()
+
+	link := func(name, author string) models.Link {
+		return models.Link{Data: models.LinkData{
+			Name:           name,
+			Subreddit:      "test",
+			Author:         author,
+			AuthorFullname: author,
+			Ups:            10,
+			CreatedUTC:     float64(now.Add(-time.Hour).Unix()),
+		}}
+	}
+	for _, l := range []models.Link{link("l1", "u1"), link("l2", "u2"), link("l3", "u3")} {
+		proc.processLink(ctx, l)
+		proc.processUser(ctx, l)
+	}
+
+	removed := link("l1", "u1")
+	removed.Data.RemovedBy = "moderator"
+	deleted := link("l2", models.DeletedAuthor)
+	deleted.Data.AuthorFullname = ""
+	deleted.Data.Selftext = "[deleted]"
+	client.On("GetLinkListing", mock.Anything, "https://oauth.reddit.com/api/info", mock.MatchedBy(func(values url.Values) bool {
+		ids := strings.Split(values.Get("id"), ",")
+		slices.Sort(ids)
+		return slices.Equal(ids, []string{"l1", "l2", "l3"})
+	})).Once().Return(models.Listing{
+		Data: models.ListingData{Children: []models.Link{removed, deleted, link("l3", "u3")}},
+	}, nil)
+
+	assert.NoError(t, proc.refreshLinks(ctx, now))
+
+	removals := proc.Removed(ctx, "", 5)
+	assert.Len(t, removals, 2)
+	reasons := map[string]models.Removal{}
+	for _, r := range removals {
+		reasons[r.Name] = r
+	}
+	assert.Equal(t, "moderator", reasons["l1"].Reason)
+	assert.Equal(t, models.RemovalDeleted, reasons["l2"].Reason)
+	assert.Equal(t, "u2", reasons["l2"].Author)
+	assert.False(t, reasons["l2"].LastSeen.IsZero())
+
+	stats, err := proc.Stats(ctx, models.StatsQuery{Limit: 5})
+	assert.NoError(t, err)
+	assert.Len(t, stats.Posts, 1)
+	assert.Equal(t, "l3", stats.Posts[0].Name)
+	assert.Len(t, stats.Users, 1)
+	assert.Equal(t, "u3", stats.Users[0].Name)
+	assert.Equal(t, &models.RemovalStats{
+		Posts:       3,
+		Removed:     1,
+		Deleted:     1,
+		RemovalRate: 2.0 / 3,
+		Reasons: []models.RemovalReason{
+			{Reason: "deleted", Count: 1},
+			{Reason: "moderator", Count: 1},
+		},
+	}, stats.Removals)
+
+	stats, err = proc.Stats(ctx, models.StatsQuery{Limit: 5, IncludeRemoved: true})
+	assert.NoError(t, err)
+	assert.Len(t, stats.Posts, 3)
+	assert.Len(t, stats.Users, 3)
+
+	// removed links are no longer refreshed
+	client.On("GetLinkListing", mock.Anything, "https://oauth.reddit.com/api/info", mock.MatchedBy(func(values url.Values) bool {
+		return values.Get("id") == "l3"
+	})).Once().Return(models.Listing{}, nil)
+	assert.NoError(t, proc.refreshLinks(ctx, now))
+}
+
+func Test_ProcessorRefreshFinalScores(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, targetConfig{Name: "test"}).(*processor)
+	now := time.Now()
+
+	link := func(name string, age time.Duration) models.Link {
+		return models.Link{Data: models.LinkData{Name: name, Ups: 10, CreatedUTC: float64(now.Add(-age).Unix())}}
+	}
+	// only links observed early on that are past the horizon are looked up
+	for _, l := range []models.Link{link("l1", 25*time.Hour), link("l2", time.Hour), link("l3", 25*time.Hour)} {
+		proc.links[l.Data.Name] = l
+	}
+	proc.recordScore(link("l1", 25*time.Hour), now.Add(-25*time.Hour+10*time.Minute))
+	proc.recordScore(link("l2", time.Hour), now.Add(-time.Hour+10*time.Minute))
+
+	client.On("GetLinkListing", mock.Anything, "https://oauth.reddit.com/api/info", mock.MatchedBy(func(values url.Values) bool {
+		return values.Get("id") == "l1"
+	})).Once().Return(models.Listing{
+		Data: models.ListingData{Children: []models.Link{link("l1", 25*time.Hour)}},
+	}, nil)
+	assert.NoError(t, proc.refreshFinalScores(ctx, now))
+	assert.NotNil(t, proc.observations["l1"].final)
+
+	// nothing is left waiting so the API isn't called
+	assert.NoError(t, proc.refreshFinalScores(ctx, now))
+}
+
+func Test_MediaStats(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	posts := []models.LinkStats{
+		{Name: "l1", Domain: "i.redd.it", Type: models.PostImage, UpVotes: 10, Created: now.Add(-time.Hour)},
+		{Name: "l2", Domain: "i.redd.it", Type: models.PostImage, UpVotes: 20, Created: now.Add(-2 * time.Hour)},
+		{Name: "l3", Domain: "YouTube.com", Type: models.PostVideo, UpVotes: 100, Created: now.Add(-3 * time.Hour)},
+		{Name: "l4", Domain: "self.test", Type: models.PostSelf, UpVotes: 5, Created: now.Add(-4 * time.Hour)},
+		{Name: "l5", Domain: "example.com", Type: models.PostLink, UpVotes: 1000, Created: now.Add(-48 * time.Hour)},
+	}
+
+	stats := mediaStats(posts, 24*time.Hour, 5, now)
+	assert.Equal(t, now.Add(-24*time.Hour), stats.From)
+	assert.Equal(t, []models.MediaCount{
+		{Name: "i.redd.it", PostCount: 2, TotalUpVotes: 30, AverageUpVotes: 15},
+		{Name: "youtube.com", PostCount: 1, TotalUpVotes: 100, AverageUpVotes: 100},
+	}, stats.DomainsByPosts)
+	assert.Equal(t, []models.MediaCount{
+		{Name: "youtube.com", PostCount: 1, TotalUpVotes: 100, AverageUpVotes: 100},
+		{Name: "i.redd.it", PostCount: 2, TotalUpVotes: 30, AverageUpVotes: 15},
+	}, stats.DomainsByUpVotes)
+	assert.Equal(t, []models.MediaCount{
+		{Name: "image", PostCount: 2, TotalUpVotes: 30, AverageUpVotes: 15},
+		{Name: "self", PostCount: 1, TotalUpVotes: 5, AverageUpVotes: 5},
+		{Name: "video", PostCount: 1, TotalUpVotes: 100, AverageUpVotes: 100},
+	}, stats.Types)
+
+	// without a window every post is counted
+	stats = mediaStats(posts, 0, 1, now)
+	assert.Equal(t, []models.MediaCount{
+		{Name: "example.com", PostCount: 1, TotalUpVotes: 1000, AverageUpVotes: 1000},
+	}, stats.DomainsByUpVotes)
+	assert.Len(t, stats.Types, 4)
+}
+
+func Test_FlairStats(t *testing.T) {
+	posts := []models.LinkStats{
+		{Name: "l1", Flair: "OC", AuthorFlair: "Artist", UpVotes: 10},
+		{Name: "l2", Flair: "OC", UpVotes: 30},
+		{Name: "l3", Flair: "OC", AuthorFlair: "Artist", UpVotes: 20},
+		{Name: "l4", Flair: "Meme", UpVotes: 100},
+		{Name: "l5", UpVotes: 1000},
+	}
+
+	stats := flairStats(posts, 2)
+	assert.Equal(t, []models.FlairCount{
+		{Flair: "Meme", PostCount: 1, TotalUpVotes: 100, AverageUpVotes: 100, Posts: []models.LinkStats{posts[3]}},
+		{Flair: "OC", PostCount: 3, TotalUpVotes: 60, AverageUpVotes: 20, Posts: []models.LinkStats{posts[1], posts[2]}},
+	}, stats.Link)
+	assert.Equal(t, []models.FlairCount{
+		{Flair: "Artist", PostCount: 2, TotalUpVotes: 30, AverageUpVotes: 15, Posts: []models.LinkStats{posts[2], posts[0]}},
+	}, stats.Author)
+}
+
+func Test_ProcessorHeatmap(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	config := targetConfig{Name: "test", Heatmap: heatmapConfig{Timezone: "America/New_York"}}
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, config).(*processor)
+
+	// 2024-01-01 is a Monday
+	monday := time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)
+	tuesday := time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)
+	for i, l := range []struct {
+		created  time.Time
+		ups      int
+		selftext string
+	}{
+		{created: monday, ups: 10},
+		{created: monday.Add(time.Minute), ups: 30},
+		{created: monday.Add(2 * time.Minute), ups: 20},
+		{created: monday.Add(3 * time.Minute), ups: 1000, selftext: "[removed]"},
+		{created: tuesday, ups: 100},
+		{created: tuesday.Add(time.Minute), ups: 1},
+		{created: tuesday.Add(2 * time.Minute), ups: 2},
+		{created: tuesday.Add(24 * time.Hour), ups: 5},
+	} {
+		name := fmt.Sprintf("l%d", i)
+		proc.links[name] = models.Link{Data: models.LinkData{
+			Name:       name,
+			Selftext:   l.selftext,
+			Ups:        l.ups,
+			CreatedUTC: float64(l.created.Unix()),
+		}}
+	}
+
+	heatmap, err := proc.Heatmap(ctx, models.HeatmapQuery{Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, "America/New_York", heatmap.Timezone)
+	assert.Equal(t, 7, heatmap.Posts)
+	assert.Len(t, heatmap.Cells, 7*24)
+	assert.Equal(t, models.HeatmapCell{Day: "Monday", Hour: 9, PostCount: 3, MedianUpVotes: 20}, heatmap.Cells[24+9])
+	assert.Equal(t, []models.HeatmapCell{
+		{Day: "Monday", Hour: 9, PostCount: 3, MedianUpVotes: 20},
+		{Day: "Monday", Hour: 21, PostCount: 3, MedianUpVotes: 2},
+	}, heatmap.Best)
+
+	// the query's timezone takes precedence over the configured one
+	heatmap, err = proc.Heatmap(ctx, models.HeatmapQuery{Timezone: "UTC", Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.HeatmapCell{
+		{Day: "Monday", Hour: 14, PostCount: 3, MedianUpVotes: 20},
+	}, heatmap.Best)
+
+	_, err = proc.Heatmap(ctx, models.HeatmapQuery{Timezone: "Nowhere/Special"})
+	assert.Error(t, err)
+}
+
+func Test_ProcessorAnomalies(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	notifier := mocks.NewNotifier(t)
+	config := targetConfig{Name: "golang+rust", Anomalies: anomaliesConfig{Enabled: true, Interval: time.Hour}}
+	proc := NewProcessor(logger, client, nil, notifier, nil, nil, config).(*processor)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// each interval ends at the hour after the posts in it were created and every poll still
+	// returns the posts from the previous interval
+	counts := []int{10, 9, 11, 10, 10, 9, 11, 40}
+	previous := []models.Link{}
+	poll := func(hour int) {
+		links := []models.Link{}
+		for i := range counts[hour] + 5 {
+			name := fmt.Sprintf("l%d_%d", hour, i)
+			subreddit := "golang"
+			if i >= counts[hour] {
+				subreddit = "rust"
+			}
+			created := start.Add(time.Duration(hour)*time.Hour + time.Duration(i)*time.Minute)
+			links = append(links, models.Link{Data: models.LinkData{Name: name, Subreddit: subreddit, CreatedUTC: float64(created.Unix())}})
+		}
+		proc.recordArrivals(slices.Concat(links, previous), start.Add(time.Duration(hour)*time.Hour+59*time.Minute))
+		previous = links
+		proc.checkVolume(ctx, start.Add(time.Duration(hour+1)*time.Hour))
+	}
+
+	for hour := range 6 {
+		poll(hour)
+	}
+	anomaly, ok := proc.Anomaly(ctx, "golang")
+	assert.True(t, ok)
+	assert.Equal(t, models.VolumeWarmingUp, anomaly.State)
+
+	poll(6)
+	anomaly, _ = proc.Anomaly(ctx, "golang")
+	assert.Equal(t, models.VolumeNormal, anomaly.State)
+	assert.Equal(t, 11, anomaly.Count)
+
+	// both the multireddit and the subreddit that surged raise alerts
+	notifier.On("Notify", ctx, mock.MatchedBy(func(a models.Alert) bool {
+		return a.Type == models.AlertVolumeSpike && a.Target == "golang+rust"
+	})).Twice().Return(nil)
+	poll(7)
+	anomaly, _ = proc.Anomaly(ctx, "golang")
+	assert.Equal(t, "golang", anomaly.Subreddit)
+	assert.Equal(t, models.VolumeSpike, anomaly.State)
+	assert.Equal(t, start.Add(8*time.Hour), anomaly.Since)
+	assert.Equal(t, 40, anomaly.Count)
+	assert.Greater(t, anomaly.ZScore, 3.0)
+	assert.Len(t, anomaly.Points, 8)
+
+	anomaly, _ = proc.Anomaly(ctx, "rust")
+	assert.Equal(t, models.VolumeNormal, anomaly.State)
+	assert.Equal(t, 5, anomaly.Count)
+	anomaly, _ = proc.Anomaly(ctx, "")
+	assert.Equal(t, "golang+rust", anomaly.Subreddit)
+	assert.Equal(t, models.VolumeSpike, anomaly.State)
+	assert.Equal(t, 45, anomaly.Count)
+
+	// disabled targets don't report an anomaly state
+	_, ok = NewProcessor(logger, client, nil, nil, nil, nil, targetConfig{Name: "other"}).Anomaly(ctx, "")
+	assert.False(t, ok)
+}
+
+func Test_ProcessorRetention(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	notifier := mocks.NewNotifier(t)
+	config := targetConfig{Name: "test", Alerts: true, Retention: retentionConfig{MaxAge: 24 * time.Hour, MaxPosts: 2}}
+	reposts := newRepostIndex()
+	proc := NewProcessor(logger, client, nil, notifier, reposts, nil, config).(*processor)
+	now := time.Now()
+
+	titles := []string{"Gophers on parade", "Rust borrow checker tips", "Python packaging woes", "Zig comptime tricks", "Odin first impressions"}
+	links := []models.Link{}
+	for i, age := range []time.Duration{48 * time.Hour, 3 * time.Hour, 2 * time.Hour, time.Hour, time.Minute} {
+		links = append(links, models.Link{Data: models.LinkData{
+			Name:           fmt.Sprintf("l%d", i),
+			Subreddit:      "test",
+			Title:          titles[i],
+			Author:         fmt.Sprintf("u%d", i%2),
+			AuthorFullname: fmt.Sprintf("t2_u%d", i%2),
+			URL:            fmt.Sprintf("https://example.com/%d", i),
+			Ups:            i,
+			CreatedUTC:     float64(now.Add(-age).Unix()),
+		}})
+	}
+	for _, link := range links[:4] {
+		proc.processLink(ctx, link)
+		proc.processUser(ctx, link)
+	}
+	proc.comments["c1"] = models.Comment{Data: models.CommentData{Name: "c1", LinkID: "l0"}}
+	proc.comments["c2"] = models.Comment{Data: models.CommentData{Name: "c2", LinkID: "l3"}}
+	proc.updateMemory()
+	before := proc.Status()
+	assert.Equal(t, 4, before.Links)
+	assert.Equal(t, 2, before.Users)
+
+	// l0 is too old and l1 is the oldest beyond the limit of 2 posts
+	assert.Equal(t, 2, proc.evict(ctx, now))
+	assert.Equal(t, []string{"l2", "l3"}, slices.Sorted(maps.Keys(proc.links)))
+	assert.Len(t, proc.history, 2)
+	assert.Len(t, proc.titles, 2)
+	assert.Equal(t, []string{"c2"}, slices.Sorted(maps.Keys(proc.comments)))
+	assert.Len(t, reposts.posts, 2)
+	assert.Len(t, reposts.urls, 2)
+
+	// users keep only their remaining posts
+	stats, err := proc.Stats(ctx, models.StatsQuery{Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.UserStats{
+		{Name: "u0", PostCount: 1},
+		{Name: "u1", PostCount: 1},
+	}, stats.Users)
+
+	after := proc.Status()
+	assert.Equal(t, 2, after.Links)
+	assert.Less(t, after.MemoryBytes, before.MemoryBytes)
+	assert.Equal(t, 0, proc.evict(ctx, now))
+
+	// evicted posts that are polled again aren't tracked or alerted on again
+	alerted := make(chan models.Alert, 1)
+	client.On("GetLinkListing", mock.Anything, mock.Anything, mock.Anything).Once().Return(models.Listing{
+		Data: models.ListingData{Children: links},
+	}, nil)
+	notifier.On("Notify", mock.Anything, mock.Anything).Once().Run(func(args mock.Arguments) {
+		alerted <- args.Get(1).(models.Alert)
+	}).Return(nil)
+	proc.process(ctx)
+	select {
+	case a := <-alerted:
+		assert.Contains(t, a.Title, "Odin first impressions")
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert for the new post")
+	}
+	assert.Eventually(t, func() bool { return proc.tracked("l4") }, time.Second, 10*time.Millisecond)
+	assert.False(t, proc.tracked("l0"))
+	assert.False(t, proc.tracked("l1"))
+}
+
+func Test_ProcessorRetentionUntracked(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	config := targetConfig{Name: "test", Retention: retentionConfig{MaxAge: 24 * time.Hour, MaxComments: 2}}
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, config).(*processor)
+	now := time.Now()
+
+	// the comment stream and sampled listings include posts that were never tracked
+	comment := func(name string, age time.Duration) models.Comment {
+		return models.Comment{Data: models.CommentData{Name: name, LinkID: "t3_other", CreatedUTC: float64(now.Add(-age).Unix())}}
+	}
+	for i, age := range []time.Duration{48 * time.Hour, 3 * time.Hour, 2 * time.Hour, time.Hour} {
+		c := comment(fmt.Sprintf("c%d", i), age)
+		proc.comments[c.Data.Name] = c
+		proc.commentSentiment[c.Data.Name] = 0
+	}
+	proc.rankings["r1"] = &ranking{listings: map[string]*listingRank{"hot": {lastSeen: now.Add(-48 * time.Hour)}}}
+	proc.rankings["r2"] = &ranking{listings: map[string]*listingRank{"hot": {lastSeen: now.Add(-48 * time.Hour)}, "rising": {lastSeen: now.Add(-time.Hour)}}}
+
+	// c0 is too old and c1 is the oldest beyond the limit of 2 comments
+	assert.Equal(t, 0, proc.evict(ctx, now))
+	assert.Equal(t, []string{"c2", "c3"}, slices.Sorted(maps.Keys(proc.comments)))
+	assert.Equal(t, []string{"c2", "c3"}, slices.Sorted(maps.Keys(proc.commentSentiment)))
+	assert.Equal(t, []string{"r2"}, slices.Sorted(maps.Keys(proc.rankings)))
+}
+
+func Test_ProcessorMetadata(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	client := mocks.NewClient(t)
+	proc := NewProcessor(logger, client, nil, nil, nil, nil, targetConfig{Name: "test"}).(*processor)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	about := func(subscribers, active int) models.SubredditAbout {
+		return models.SubredditAbout{Data: models.SubredditAboutData{
+			DisplayName:     "test",
+			Subscribers:     subscribers,
+			ActiveUserCount: active,
+			Over18:          true,
+			CreatedUTC:      float64(now.Add(-24 * time.Hour).Unix()),
+		}}
+	}
+
+	assert.Nil(t, proc.subredditStats())
+
+	client.On("GetSubredditAbout", mock.Anything, "https://oauth.reddit.com/r/test/about", mock.Anything).Once().Return(about(100, 5), nil)
+	client.On("GetSubredditAbout", mock.Anything, "https://oauth.reddit.com/r/test/about", mock.Anything).Once().Return(models.SubredditAbout{}, errors.New("failed to call api"))
+	client.On("GetSubredditAbout", mock.Anything, "https://oauth.reddit.com/r/test/about", mock.Anything).Once().Return(about(150, 9), nil)
+	assert.NoError(t, proc.fetchMetadata(ctx, now))
+	assert.Error(t, proc.fetchMetadata(ctx, now.Add(15*time.Minute)))
+	assert.NoError(t, proc.fetchMetadata(ctx, now.Add(30*time.Minute)))
+
+	stats, err := proc.Stats(ctx, models.StatsQuery{Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.SubredditStats{
+		Name:             "test",
+		Subscribers:      150,
+		ActiveUsers:      9,
+		NSFW:             true,
+		Created:          now.Add(-24 * time.Hour).Local(),
+		SubscriberGrowth: 50,
+		Points: []models.SubredditPoint{
+			{Time: now, Subscribers: 100, ActiveUsers: 5},
+			{Time: now.Add(30 * time.Minute), Subscribers: 150, ActiveUsers: 9},
+		},
+	}, stats.Subreddit)
+}
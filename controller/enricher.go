@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/client"
+	"github.com/jgkawell/reddit-api-demo/models"
+
+	"github.com/steady-bytes/draft/pkg/chassis"
+	"golang.org/x/time/rate"
+)
+
+type (
+	// Enricher fetches account details for the authors tracked by every Processor. Fetches
+	// are made in the background, cached for the configured TTL and limited to the configured
+	// budget (on top of the rate limit of the shared Client) so that enrichment never starves
+	// stat collection.
+	Enricher interface {
+		// Start processes queued authors forever and is meant to be run on a background routine.
+		Start()
+		// Track queues the author to be fetched if they aren't cached or their cache is stale.
+		Track(name string)
+		// Account returns the cached account details of the author (nil if not fetched yet).
+		Account(name string) *models.Account
+	}
+	enricher struct {
+		logger  chassis.Logger
+		client  client.Client
+		ttl     time.Duration
+		limiter *rate.Limiter
+
+		mu       sync.RWMutex
+		accounts map[string]*models.Account
+		queued   map[string]bool
+		queue    chan string
+	}
+	// enrichmentConfig enables fetching account details of tracked authors. Budget is the
+	// maximum number of fetches per minute.
+	enrichmentConfig struct {
+		Enabled bool
+		TTL     time.Duration
+		Budget  int
+	}
+)
+
+const (
+	defaultEnrichmentTTL    = 24 * time.Hour
+	defaultEnrichmentBudget = 30
+	enrichmentQueueSize     = 1000
+)
+
+func NewEnricher(logger chassis.Logger, client client.Client, config enrichmentConfig) Enricher {
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = defaultEnrichmentTTL
+	}
+	budget := config.Budget
+	if budget <= 0 {
+		budget = defaultEnrichmentBudget
+	}
+	return &enricher{
+		logger:  logger.WithField("worker", "enricher"),
+		client:  client,
+		ttl:     ttl,
+		limiter: rate.NewLimiter(rate.Limit(float64(budget)/60), 1),
+
+		mu:       sync.RWMutex{},
+		accounts: make(map[string]*models.Account),
+		queued:   make(map[string]bool),
+		queue:    make(chan string, enrichmentQueueSize),
+	}
+}
+
+func (e *enricher) Start() {
+	ctx := context.Background()
+	for name := range e.queue {
+		e.mu.Lock()
+		delete(e.queued, name)
+		e.mu.Unlock()
+		if e.fresh(name) {
+			continue
+		}
+
+		err := e.limiter.Wait(ctx)
+		if err != nil {
+			e.logger.WithError(err).Error("failed to wait for enrichment budget")
+			continue
+		}
+		err = e.enrich(ctx, name)
+		if err != nil {
+			e.logger.WithError(err).WithField("user", name).Warn("failed to enrich user")
+		}
+	}
+}
+
+func (e *enricher) Track(name string) {
+	// deleted accounts have no profile to fetch
+	if name == "" || name == "[deleted]" || e.fresh(name) {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.queued[name] {
+		return
+	}
+	select {
+	case e.queue <- name:
+		e.queued[name] = true
+	default:
+		e.logger.WithField("user", name).Debug("enrichment queue full, skipping user")
+	}
+}
+
+func (e *enricher) Account(name string) *models.Account {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.accounts[name]
+}
+
+// enrich fetches and caches the account details of the author. Accounts that no longer exist
+// are cached as well so they aren't fetched again until the TTL expires.
+func (e *enricher) enrich(ctx context.Context, name string) error {
+	about, err := e.client.GetUserAbout(ctx, userURL(name, "about"), nil)
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		return err
+	}
+
+	account := &models.Account{
+		FetchedAt: time.Now(),
+	}
+	if errors.Is(err, client.ErrNotFound) {
+		account.Deleted = true
+	} else {
+		account.Created = time.Unix(int64(about.Data.CreatedUTC), 0)
+		account.LinkKarma = about.Data.LinkKarma
+		account.CommentKarma = about.Data.CommentKarma
+		account.Verified = about.Data.Verified
+		account.Suspended = about.Data.IsSuspended
+	}
+
+	e.mu.Lock()
+	e.accounts[name] = account
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *enricher) fresh(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	account, ok := e.accounts[name]
+	return ok && time.Since(account.FetchedAt) < e.ttl
+}
+
+func userURL(name string, path string) string {
+	return fmt.Sprintf("https://oauth.reddit.com/user/%s/%s", name, path)
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+// linkFilter is the compiled form of a models.LinkFilter. The zero value matches every link.
+type linkFilter struct {
+	title               *regexp.Regexp
+	excludeTitle        *regexp.Regexp
+	flairs              []string
+	excludeFlairs       []string
+	authorFlairs        []string
+	excludeAuthorFlairs []string
+	domains             []string
+	excludeDomains      []string
+	types               []models.PostType
+	authors             []string
+	excludeAuthors      []string
+	nsfw                *bool
+	stickied            *bool
+	minUpVotes          int
+	subreddit           string
+	visible             bool
+}
+
+// newLinkFilter compiles the filter, returning an error if either title expression is invalid.
+// Title expressions are case insensitive as are flairs, domains and authors.
+func newLinkFilter(f models.LinkFilter) (filter linkFilter, err error) {
+	filter = linkFilter{
+		flairs:              lower(f.Flairs),
+		excludeFlairs:       lower(f.ExcludeFlairs),
+		authorFlairs:        lower(f.AuthorFlairs),
+		excludeAuthorFlairs: lower(f.ExcludeAuthorFlairs),
+		domains:             lower(f.Domains),
+		excludeDomains:      lower(f.ExcludeDomains),
+		types:               f.Types,
+		authors:             lower(f.Authors),
+		excludeAuthors:      lower(f.ExcludeAuthors),
+		nsfw:                f.NSFW,
+		stickied:            f.Stickied,
+		minUpVotes:          f.MinUpVotes,
+	}
+	if f.Title != "" {
+		filter.title, err = regexp.Compile("(?i)" + f.Title)
+		if err != nil {
+			return
+		}
+	}
+	if f.ExcludeTitle != "" {
+		filter.excludeTitle, err = regexp.Compile("(?i)" + f.ExcludeTitle)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// inSubreddit returns a copy of the filter that also requires links to be posted to the
+// subreddit. An empty subreddit matches every link.
+func (f linkFilter) inSubreddit(subreddit string) linkFilter {
+	f.subreddit = subreddit
+	return f
+}
+
+// onlyVisible returns a copy of the filter that also leaves out removed and deleted links.
+func (f linkFilter) onlyVisible() linkFilter {
+	f.visible = true
+	return f
+}
+
+func (f linkFilter) match(link models.LinkData) bool {
+	if f.subreddit != "" && !strings.EqualFold(link.Subreddit, f.subreddit) {
+		return false
+	}
+	if f.visible && link.Removal() != "" {
+		return false
+	}
+	if f.title != nil && !f.title.MatchString(link.Title) {
+		return false
+	}
+	if f.excludeTitle != nil && f.excludeTitle.MatchString(link.Title) {
+		return false
+	}
+	if !allowed(f.flairs, f.excludeFlairs, strings.ToLower(link.LinkFlairText)) {
+		return false
+	}
+	if !allowed(f.authorFlairs, f.excludeAuthorFlairs, strings.ToLower(link.AuthorFlairText)) {
+		return false
+	}
+	if !allowed(f.domains, f.excludeDomains, strings.ToLower(link.Domain)) {
+		return false
+	}
+	if !allowed(f.authors, f.excludeAuthors, strings.ToLower(link.Author)) {
+		return false
+	}
+	if len(f.types) > 0 && !slices.Contains(f.types, link.Type()) {
+		return false
+	}
+	if f.nsfw != nil && *f.nsfw != link.Over18 {
+		return false
+	}
+	if f.stickied != nil && *f.stickied != link.Stickied {
+		return false
+	}
+	return f.minUpVotes == 0 || link.Ups >= f.minUpVotes
+}
+
+// filterLinks returns the links that match the filter.
+func filterLinks(links map[string]models.Link, filter linkFilter) map[string]models.Link {
+	result := map[string]models.Link{}
+	for name, l := range links {
+		if filter.match(l.Data) {
+			result[name] = l
+		}
+	}
+	return result
+}
+
+// allowed checks the value against an allow list (if any) and a deny list.
+func allowed(allow []string, deny []string, value string) bool {
+	if len(allow) > 0 && !slices.Contains(allow, value) {
+		return false
+	}
+	return !slices.Contains(deny, value)
+}
+
+func lower(values []string) []string {
+	result := []string{}
+	for _, v := range values {
+		result = append(result, strings.ToLower(v))
+	}
+	return result
+}
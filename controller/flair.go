@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+// flairStats aggregates the posts by their link flair and by their author's flair, ranking the
+// flairs by their average upvotes. Posts without a flair are left out.
+func flairStats(posts []models.LinkStats, limit int) *models.FlairStats {
+	return &models.FlairStats{
+		Link: flairCounts(posts, limit, func(l models.LinkStats) string {
+			return l.Flair
+		}),
+		Author: flairCounts(posts, limit, func(l models.LinkStats) string {
+			return l.AuthorFlair
+		}),
+	}
+}
+
+func flairCounts(posts []models.LinkStats, limit int, flair func(models.LinkStats) string) []models.FlairCount {
+	byFlair := map[string][]models.LinkStats{}
+	for _, l := range posts {
+		if f := flair(l); f != "" {
+			byFlair[f] = append(byFlair[f], l)
+		}
+	}
+
+	result := []models.FlairCount{}
+	for f, flaired := range byFlair {
+		c := models.FlairCount{
+			Flair:     f,
+			PostCount: len(flaired),
+		}
+		for _, l := range flaired {
+			c.TotalUpVotes += l.UpVotes
+		}
+		c.AverageUpVotes = float64(c.TotalUpVotes) / float64(c.PostCount)
+		slices.SortFunc(flaired, func(a, b models.LinkStats) int {
+			return cmp.Or(b.UpVotes-a.UpVotes, strings.Compare(a.Name, b.Name))
+		})
+		c.Posts = truncate(flaired, limit)
+		result = append(result, c)
+	}
+	slices.SortFunc(result, func(a, b models.FlairCount) int {
+		return cmp.Or(cmpDesc(a.AverageUpVotes, b.AverageUpVotes), b.PostCount-a.PostCount, strings.Compare(a.Flair, b.Flair))
+	})
+	return truncate(result, limit)
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+type (
+	// heatmapConfig sets the IANA timezone (e.g. "America/New_York") that posting times are
+	// bucketed in. Defaults to UTC.
+	heatmapConfig struct {
+		Timezone string
+	}
+)
+
+const (
+	// cells need at least this many posts to be ranked as one of the best times to post
+	minHeatmapPosts = 3
+)
+
+// Heatmap buckets the tracked posts by the day of the week and hour of the day they were created
+// at in the query's timezone (or the configured one) with the number of posts and median score
+// of each bucket. Removed and deleted posts are left out.
+func (p *processor) Heatmap(_ context.Context, query models.HeatmapQuery) (heatmap models.Heatmap, err error) {
+	timezone := cmp.Or(query.Timezone, p.config.Heatmap.Timezone, "UTC")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return heatmap, err
+	}
+	filter := linkFilter{}.inSubreddit(query.Subreddit)
+
+	upVotes := [7][24][]float64{}
+	heatmap = models.Heatmap{
+		Subreddit: cmp.Or(query.Subreddit, p.config.key()),
+		Timezone:  location.String(),
+		Cells:     []models.HeatmapCell{},
+		Best:      []models.HeatmapCell{},
+	}
+	p.linksMu.RLock()
+	for _, l := range p.links {
+		if l.Data.CreatedUTC == 0 || l.Data.Removal() != "" || !filter.match(l.Data) {
+			continue
+		}
+		created := l.Data.Created().In(location)
+		cell := &upVotes[created.Weekday()][created.Hour()]
+		*cell = append(*cell, float64(l.Data.Ups))
+		heatmap.Posts++
+	}
+	p.linksMu.RUnlock()
+
+	for day, hours := range upVotes {
+		for hour, ups := range hours {
+			cell := models.HeatmapCell{
+				Day:           time.Weekday(day).String(),
+				Hour:          hour,
+				PostCount:     len(ups),
+				MedianUpVotes: median(ups),
+			}
+			heatmap.Cells = append(heatmap.Cells, cell)
+			if cell.PostCount >= minHeatmapPosts {
+				heatmap.Best = append(heatmap.Best, cell)
+			}
+		}
+	}
+	slices.SortStableFunc(heatmap.Best, func(a, b models.HeatmapCell) int {
+		return cmp.Or(cmpDesc(a.MedianUpVotes, b.MedianUpVotes), b.PostCount-a.PostCount)
+	})
+	heatmap.Best = truncate(heatmap.Best, query.Limit)
+
+	return heatmap, nil
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+type (
+	// resolution is the spacing kept between score points once they are older than age
+	resolution struct {
+		age     time.Duration
+		spacing time.Duration
+	}
+)
+
+const (
+	// refreshes closer together than this replace the latest point instead of appending
+	minScoreSpacing = 10 * time.Second
+)
+
+// resolutions are ordered from oldest to newest, points younger than the last tier are kept
+// at full resolution
+var resolutions = []resolution{
+	{age: 24 * time.Hour, spacing: time.Hour},
+	{age: time.Hour, spacing: 5 * time.Minute},
+}
+
+// History returns the score trajectory of the given link if it is being tracked.
+func (p *processor) History(_ context.Context, fullname string) (history models.PostHistory, ok bool) {
+	p.linksMu.RLock()
+	link, ok := p.links[fullname]
+	p.linksMu.RUnlock()
+	if !ok {
+		return history, false
+	}
+
+	p.historyMu.RLock()
+	points := slices.Clone(p.history[fullname])
+	p.historyMu.RUnlock()
+
+	return models.PostHistory{
+		Name:      link.Data.Name,
+		Subreddit: link.Data.Subreddit,
+		Title:     link.Data.Title,
+		Points:    points,
+	}, true
+}
+
+// recordScore adds the link's current score to its history and the prediction observations.
+func (p *processor) recordScore(link models.Link, now time.Time) {
+	point := models.ScorePoint{
+		Time:        now,
+		Ups:         link.Data.Ups,
+		UpvoteRatio: link.Data.UpvoteRatio,
+		NumComments: link.Data.NumComments,
+	}
+
+	p.historyMu.Lock()
+	defer p.historyMu.Unlock()
+	points := p.history[link.Data.Name]
+	if n := len(points); n > 0 && now.Sub(points[n-1].Time) < minScoreSpacing {
+		points[n-1] = point
+	} else {
+		points = append(points, point)
+	}
+	p.history[link.Data.Name] = downsample(points, scorePointTime, now)
+	p.observe(link, point)
+}
+
+// downsample thins out older points according to the configured resolutions, keeping the
+// latest point within each interval.
+func downsample[T any](points []T, at func(T) time.Time, now time.Time) []T {
+	result := points[:0]
+	for i, point := range points {
+		if i+1 < len(points) {
+			t := at(point)
+			spacing := spacingFor(now.Sub(t))
+			next := at(points[i+1])
+			if spacing > 0 && t.Truncate(spacing).Equal(next.Truncate(spacing)) {
+				// a later point covers the same interval
+				continue
+			}
+		}
+		result = append(result, point)
+	}
+	return result
+}
+
+func spacingFor(age time.Duration) time.Duration {
+	for _, r := range resolutions {
+		if age >= r.age {
+			return r.spacing
+		}
+	}
+	return 0
+}
+
+func scorePointTime(p models.ScorePoint) time.Time {
+	return p.Time
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
+)
+
+type (
+	// listingsConfig selects additional listings to sample (e.g. "hot", "rising", "top?t=day"
+	// or "controversial") and how often to sample them.
+	listingsConfig struct {
+		Sources  []string
+		Interval time.Duration
+	}
+	// ranking tracks where a single link has appeared on each sampled listing source (e.g.
+	// "top?t=day" and "top?t=week" are tracked separately)
+	ranking struct {
+		title     string
+		subreddit string
+		listings  map[string]*listingRank
+	}
+	listingRank struct {
+		firstSeen time.Time
+		lastSeen  time.Time
+		best      int
+		// duration only counts the time between consecutive samples that both had the link
+		// on the front page so that time spent off of it isn't included
+		duration time.Duration
+		// points are only appended when the rank changes
+		points []models.RankPoint
+	}
+)
+
+const (
+	// the number of links shown on the first page of a listing
+	frontPageSize          = 25
+	defaultListingInterval = time.Minute
+)
+
+// startListings samples the configured listings forever and is meant to be run on a background
+// routine.
+func (p *processor) startListings(ctx context.Context) {
+	interval := p.config.Listings.Interval
+	if interval <= 0 {
+		interval = defaultListingInterval
+	}
+	for {
+		for _, source := range p.config.Listings.Sources {
+			err := p.sampleListing(ctx, source, time.Now())
+			if err != nil {
+				p.logger.WithError(err).WithField("listing", source).Warn("failed to sample listing")
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// sampleListing fetches the front page of the given listing source and records the rank of
+// each link on it.
+func (p *processor) sampleListing(ctx context.Context, source string, now time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "processor.listing")
+	span.SetAttributes(
+		tracing.SubredditKey.String(p.config.Name),
+		tracing.ListingKey.String(source),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	sort, query, _ := strings.Cut(source, "?")
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return err
+	}
+	values.Set("limit", strconv.Itoa(frontPageSize))
+	listing, err := p.client.GetLinkListing(ctx, p.listingURL(sort, values), values)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(tracing.ResultCountKey.Int(len(listing.Data.Children)))
+
+	p.rankingsMu.Lock()
+	defer p.rankingsMu.Unlock()
+	previous := p.sampled[source]
+	p.sampled[source] = now
+	for i, link := range listing.Data.Children {
+		rank := i + 1
+		r, ok := p.rankings[link.Data.Name]
+		if !ok {
+			r = &ranking{
+				listings: map[string]*listingRank{},
+			}
+			p.rankings[link.Data.Name] = r
+		}
+		r.title = link.Data.Title
+		r.subreddit = link.Data.Subreddit
+
+		lr, ok := r.listings[source]
+		if !ok {
+			lr = &listingRank{
+				firstSeen: now,
+				best:      rank,
+			}
+			r.listings[source] = lr
+		}
+		if ok && lr.lastSeen.Equal(previous) {
+			lr.duration += now.Sub(previous)
+		}
+		lr.lastSeen = now
+		lr.best = min(lr.best, rank)
+		if len(lr.points) == 0 || lr.points[len(lr.points)-1].Rank != rank {
+			lr.points = append(lr.points, models.RankPoint{
+				Time: now,
+				Rank: rank,
+			})
+		}
+	}
+	return nil
+}
+
+// Rankings reports how long links stayed on the front page of each sampled listing along with
+// the links that made it from rising to hot. If a subreddit is given only its links are
+// included.
+func (p *processor) Rankings(_ context.Context, subreddit string, limit int) models.Rankings {
+	rankings := models.Rankings{
+		Listings:    []models.ListingRankings{},
+		RisingToHot: []models.RisingToHotStats{},
+	}
+	byListing := map[string][]models.RankedLinkStats{}
+
+	p.rankingsMu.RLock()
+	p.removalsMu.RLock()
+	for name, r := range p.rankings {
+		if subreddit != "" && !strings.EqualFold(r.subreddit, subreddit) {
+			continue
+		}
+		for listing, lr := range r.listings {
+			byListing[listing] = append(byListing[listing], models.RankedLinkStats{
+				Name:            name,
+				Title:           r.title,
+				Removed:         p.removals[name].Reason,
+				BestRank:        lr.best,
+				LatestRank:      lr.points[len(lr.points)-1].Rank,
+				FirstSeen:       lr.firstSeen,
+				LastSeen:        lr.lastSeen,
+				DurationSeconds: int64(lr.duration.Seconds()),
+				Ranks:           slices.Clone(lr.points),
+			})
+		}
+		rising, wasRising := r.listings["rising"]
+		hot, isHot := r.listings["hot"]
+		if wasRising && isHot && rising.firstSeen.Before(hot.firstSeen) {
+			rankings.RisingToHot = append(rankings.RisingToHot, models.RisingToHotStats{
+				Name:     name,
+				Title:    r.title,
+				RisingAt: rising.firstSeen,
+				HotAt:    hot.firstSeen,
+			})
+		}
+	}
+	p.removalsMu.RUnlock()
+	p.rankingsMu.RUnlock()
+
+	for listing, posts := range byListing {
+		slices.SortFunc(posts, func(a, b models.RankedLinkStats) int {
+			return int(b.DurationSeconds - a.DurationSeconds)
+		})
+		rankings.Listings = append(rankings.Listings, models.ListingRankings{
+			Listing: listing,
+			Posts:   truncate(posts, limit),
+		})
+	}
+	slices.SortFunc(rankings.Listings, func(a, b models.ListingRankings) int {
+		return strings.Compare(a.Listing, b.Listing)
+	})
+	// most recent promotions first
+	slices.SortFunc(rankings.RisingToHot, func(a, b models.RisingToHotStats) int {
+		return b.HotAt.Compare(a.HotAt)
+	})
+	rankings.RisingToHot = truncate(rankings.RisingToHot, limit)
+
+	return rankings
+}
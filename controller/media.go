@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+// mediaStats ranks the domains that link posts go to by number of posts and by total upvotes
+// along with the number of posts of each media type. Only posts created within the window are
+// counted, a zero window counts every post.
+func mediaStats(posts []models.LinkStats, window time.Duration, limit int, now time.Time) *models.MediaStats {
+	stats := &models.MediaStats{}
+	if window > 0 {
+		stats.From = now.Add(-window)
+	}
+
+	domains := map[string]*models.MediaCount{}
+	types := map[string]*models.MediaCount{}
+	for _, l := range posts {
+		if l.Created.Before(stats.From) {
+			continue
+		}
+		count(types, string(l.Type), l.UpVotes)
+		if l.Type != models.PostSelf && l.Domain != "" {
+			count(domains, strings.ToLower(l.Domain), l.UpVotes)
+		}
+	}
+
+	stats.DomainsByPosts = mediaCounts(domains, func(a, b models.MediaCount) int {
+		return cmp.Or(b.PostCount-a.PostCount, b.TotalUpVotes-a.TotalUpVotes, strings.Compare(a.Name, b.Name))
+	})
+	stats.DomainsByUpVotes = slices.Clone(stats.DomainsByPosts)
+	slices.SortFunc(stats.DomainsByUpVotes, func(a, b models.MediaCount) int {
+		return cmp.Or(b.TotalUpVotes-a.TotalUpVotes, b.PostCount-a.PostCount, strings.Compare(a.Name, b.Name))
+	})
+	stats.DomainsByPosts = truncate(stats.DomainsByPosts, limit)
+	stats.DomainsByUpVotes = truncate(stats.DomainsByUpVotes, limit)
+	stats.Types = mediaCounts(types, func(a, b models.MediaCount) int {
+		return cmp.Or(b.PostCount-a.PostCount, strings.Compare(a.Name, b.Name))
+	})
+
+	return stats
+}
+
+func count(counts map[string]*models.MediaCount, name string, upVotes int) {
+	c, ok := counts[name]
+	if !ok {
+		c = &models.MediaCount{Name: name}
+		counts[name] = c
+	}
+	c.PostCount++
+	c.TotalUpVotes += upVotes
+}
+
+func mediaCounts(counts map[string]*models.MediaCount, sort func(a, b models.MediaCount) int) []models.MediaCount {
+	result := []models.MediaCount{}
+	for _, c := range counts {
+		c.AverageUpVotes = float64(c.TotalUpVotes) / float64(c.PostCount)
+		result = append(result, *c)
+	}
+	slices.SortFunc(result, sort)
+	return result
+}
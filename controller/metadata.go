@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
+)
+
+type (
+	// metadataConfig sets how often the subreddit's metadata is fetched.
+	metadataConfig struct {
+		Interval time.Duration
+	}
+)
+
+const defaultMetadataInterval = 15 * time.Minute
+
+// startMetadata fetches the subreddit's metadata forever and is meant to be run on a
+// background routine.
+func (p *processor) startMetadata(ctx context.Context) {
+	interval := p.config.Metadata.Interval
+	if interval <= 0 {
+		interval = defaultMetadataInterval
+	}
+	for {
+		err := p.fetchMetadata(ctx, time.Now())
+		if err != nil {
+			p.logger.WithError(err).Warn("failed to fetch subreddit metadata")
+		}
+		time.Sleep(interval)
+	}
+}
+
+// fetchMetadata looks up the subreddit's details and records its subscriber and active user counts.
+func (p *processor) fetchMetadata(ctx context.Context, now time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "processor.metadata")
+	span.SetAttributes(tracing.SubredditKey.String(p.config.Name))
+	defer func() { tracing.End(span, err) }()
+
+	about, err := p.client.GetSubredditAbout(ctx, subredditURL(p.config.Name, "about"), nil)
+	if err != nil {
+		return err
+	}
+
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+	p.about = &about.Data
+	p.metadata = append(p.metadata, models.SubredditPoint{
+		Time:        now,
+		Subscribers: about.Data.Subscribers,
+		ActiveUsers: about.Data.ActiveUserCount,
+	})
+	p.metadata = downsample(p.metadata, subredditPointTime, now)
+	return nil
+}
+
+// subredditStats returns the latest metadata along with the subscriber and active user curves
+// (nil if the metadata hasn't been fetched yet).
+func (p *processor) subredditStats() *models.SubredditStats {
+	p.metadataMu.RLock()
+	defer p.metadataMu.RUnlock()
+	if p.about == nil {
+		return nil
+	}
+
+	return &models.SubredditStats{
+		Name:             p.config.Name,
+		Subscribers:      p.about.Subscribers,
+		ActiveUsers:      p.about.ActiveUserCount,
+		NSFW:             p.about.Over18,
+		Quarantined:      p.about.Quarantine,
+		Created:          time.Unix(int64(p.about.CreatedUTC), 0),
+		SubscriberGrowth: p.about.Subscribers - p.metadata[0].Subscribers,
+		Points:           slices.Clone(p.metadata),
+	}
+}
+
+func subredditPointTime(p models.SubredditPoint) time.Time {
+	return p.Time
+}
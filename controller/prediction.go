@@ -0,0 +1,377 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
+)
+
+type (
+	// observation keeps the points of a link's history used by the prediction model. They are
+	// kept separately from the history itself since downsampling would otherwise thin out the
+	// early points of older links.
+	observation struct {
+		created   time.Time
+		subreddit string
+		// the latest point within the observation window
+		early *models.ScorePoint
+		// the first point at or beyond the prediction horizon
+		final *models.ScorePoint
+	}
+	// predictionModel is a least squares fit of the log of a link's final score against the log
+	// of its early score, early comment count and age at the early observation.
+	predictionModel struct {
+		coefficients []float64
+		stddev       float64
+		samples      int
+	}
+	sample struct {
+		x []float64
+		y float64
+	}
+)
+
+const (
+	// predictions are made from the trajectory over a link's first minutes
+	predictionWindow = 30 * time.Minute
+	// the age at which a link's score is predicted
+	predictionHorizon = 24 * time.Hour
+	// the model isn't fitted until there are enough links with a full trajectory
+	minPredictionSamples = 10
+	// z-score of the confidence interval
+	predictionZ          = 1.645
+	predictionConfidence = 0.9
+	backtestFolds        = 5
+	// how often links that are past the prediction horizon are looked up for their final score
+	finalScoreInterval = 5 * time.Minute
+)
+
+// ErrNotEnoughHistory is returned when too few links have a full trajectory to fit a model.
+var ErrNotEnoughHistory = errors.New("not enough history to fit a prediction model")
+
+// startPredictions looks up the final score of links that have passed the prediction horizon
+// forever and is meant to be run on a background routine. The live poll only returns the
+// newest links so without this the model would never have anything to train on.
+func (p *processor) startPredictions(ctx context.Context) {
+	for {
+		time.Sleep(finalScoreInterval)
+		err := p.refreshFinalScores(ctx, time.Now())
+		if err != nil {
+			p.logger.WithError(err).Warn("failed to refresh final scores")
+		}
+	}
+}
+
+// refreshFinalScores looks up every link that is waiting for its final score and refits the
+// prediction model with them.
+func (p *processor) refreshFinalScores(ctx context.Context, now time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "processor.predictions")
+	span.SetAttributes(tracing.SubredditKey.String(p.config.Name))
+	defer func() { tracing.End(span, err) }()
+
+	names := []string{}
+	p.linksMu.RLock()
+	p.historyMu.RLock()
+	for name := range p.links {
+		if p.awaitingFinal(name, now) {
+			names = append(names, name)
+		}
+	}
+	p.historyMu.RUnlock()
+	p.linksMu.RUnlock()
+	span.SetAttributes(tracing.ResultCountKey.Int(len(names)))
+	if len(names) == 0 {
+		return nil
+	}
+
+	err = p.lookupLinks(ctx, names, now)
+	if err != nil {
+		return err
+	}
+	p.fitPredictor()
+	return nil
+}
+
+// Backtest evaluates the prediction model with k-fold cross validation over the links whose
+// full trajectory has been collected. If a subreddit is given only its links are used.
+func (p *processor) Backtest(_ context.Context, subreddit string) (backtest models.Backtest, err error) {
+	p.historyMu.RLock()
+	samples := p.samples(subreddit)
+	p.historyMu.RUnlock()
+
+	backtest = models.Backtest{
+		Subreddit: cmp.Or(subreddit, p.config.Name),
+		Samples:   len(samples),
+		Folds:     backtestFolds,
+	}
+	if len(samples) < minPredictionSamples+backtestFolds {
+		return backtest, ErrNotEnoughHistory
+	}
+
+	var (
+		absErrors     []float64
+		percentErrors []float64
+		covered       int
+		ssRes, ssTot  float64
+		meanY         float64
+	)
+	for _, s := range samples {
+		meanY += s.y
+	}
+	meanY /= float64(len(samples))
+
+	for fold := range backtestFolds {
+		train, test := []sample{}, []sample{}
+		for i, s := range samples {
+			if i%backtestFolds == fold {
+				test = append(test, s)
+			} else {
+				train = append(train, s)
+			}
+		}
+		model, err := fitPrediction(train)
+		if err != nil {
+			return backtest, err
+		}
+		for _, s := range test {
+			y := model.predictLog(s.x)
+			prediction := model.prediction(s.x)
+			actual := math.Expm1(s.y)
+
+			absErrors = append(absErrors, math.Abs(prediction.Score-actual))
+			if actual > 0 {
+				percentErrors = append(percentErrors, math.Abs(prediction.Score-actual)/actual)
+			}
+			if actual >= prediction.Lower && actual <= prediction.Upper {
+				covered++
+			}
+			ssRes += (s.y - y) * (s.y - y)
+			ssTot += (s.y - meanY) * (s.y - meanY)
+		}
+	}
+
+	backtest.MeanAbsoluteError = mean(absErrors)
+	backtest.MedianAbsolutePercentError = median(percentErrors)
+	backtest.IntervalCoverage = float64(covered) / float64(len(samples))
+	if ssTot > 0 {
+		backtest.LogRSquared = 1 - ssRes/ssTot
+	}
+	return backtest, nil
+}
+
+// observe records the points used by the prediction model, the caller must hold historyMu.
+func (p *processor) observe(link models.Link, point models.ScorePoint) {
+	if link.Data.CreatedUTC == 0 {
+		return
+	}
+	o, ok := p.observations[link.Data.Name]
+	if !ok {
+		o = &observation{created: link.Data.Created(), subreddit: link.Data.Subreddit}
+		p.observations[link.Data.Name] = o
+	}
+	age := point.Time.Sub(o.created)
+	if age <= predictionWindow {
+		o.early = &point
+	}
+	if age >= predictionHorizon && o.final == nil {
+		o.final = &point
+		p.modelStale = true
+	}
+}
+
+// fitPredictor refits the prediction model over the current observations if they changed
+// since the last fit. The model is left nil if there isn't enough history yet.
+func (p *processor) fitPredictor() {
+	p.historyMu.Lock()
+	defer p.historyMu.Unlock()
+	if !p.modelStale {
+		return
+	}
+	p.modelStale = false
+	model, err := fitPrediction(p.samples(""))
+	if err != nil {
+		p.model = nil
+		return
+	}
+	p.model = model
+}
+
+// awaitingFinal reports whether the link was observed early on and has passed the prediction
+// horizon without its final score being recorded, the caller must hold historyMu.
+func (p *processor) awaitingFinal(fullname string, now time.Time) bool {
+	o, ok := p.observations[fullname]
+	return ok && o.early != nil && o.final == nil && now.Sub(o.created) >= predictionHorizon
+}
+
+// predict returns the predicted final score of the link if it is still within the prediction
+// horizon and was observed early enough, the caller must hold historyMu.
+func (p *processor) predict(model *predictionModel, fullname string) *models.Prediction {
+	if model == nil {
+		return nil
+	}
+	o, ok := p.observations[fullname]
+	if !ok || o.early == nil || o.final != nil {
+		return nil
+	}
+	prediction := model.prediction(features(o.created, *o.early))
+	return &prediction
+}
+
+// samples collects the training data from every link with a full trajectory in a stable
+// order, optionally only from the given subreddit. The caller must hold historyMu.
+func (p *processor) samples(subreddit string) []sample {
+	names := []string{}
+	for name, o := range p.observations {
+		if subreddit != "" && !strings.EqualFold(o.subreddit, subreddit) {
+			continue
+		}
+		if o.early != nil && o.final != nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	samples := make([]sample, 0, len(names))
+	for _, name := range names {
+		o := p.observations[name]
+		samples = append(samples, sample{
+			x: features(o.created, *o.early),
+			y: math.Log1p(float64(max(o.final.Ups, 0))),
+		})
+	}
+	return samples
+}
+
+func features(created time.Time, early models.ScorePoint) []float64 {
+	age := max(early.Time.Sub(created), time.Minute)
+	return []float64{
+		1,
+		math.Log1p(float64(max(early.Ups, 0))),
+		math.Log1p(float64(max(early.NumComments, 0))),
+		math.Log(age.Minutes()),
+	}
+}
+
+// fitPrediction solves the normal equations for the least squares fit of the samples.
+func fitPrediction(samples []sample) (*predictionModel, error) {
+	if len(samples) < minPredictionSamples {
+		return nil, ErrNotEnoughHistory
+	}
+	n := len(samples[0].x)
+
+	// build X^T X (with a small ridge to keep it invertible) and X^T y
+	xtx := make([][]float64, n)
+	xty := make([]float64, n)
+	for i := range xtx {
+		xtx[i] = make([]float64, n)
+		xtx[i][i] = 1e-6
+	}
+	for _, s := range samples {
+		for i := range n {
+			xty[i] += s.x[i] * s.y
+			for j := range n {
+				xtx[i][j] += s.x[i] * s.x[j]
+			}
+		}
+	}
+	coefficients, err := solve(xtx, xty)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &predictionModel{
+		coefficients: coefficients,
+		samples:      len(samples),
+	}
+	var sse float64
+	for _, s := range samples {
+		residual := s.y - model.predictLog(s.x)
+		sse += residual * residual
+	}
+	model.stddev = math.Sqrt(sse / float64(max(len(samples)-n, 1)))
+	return model, nil
+}
+
+func (m *predictionModel) predictLog(x []float64) float64 {
+	var y float64
+	for i, c := range m.coefficients {
+		y += c * x[i]
+	}
+	return y
+}
+
+func (m *predictionModel) prediction(x []float64) models.Prediction {
+	y := m.predictLog(x)
+	return models.Prediction{
+		Score:      math.Expm1(y),
+		Lower:      math.Expm1(y - predictionZ*m.stddev),
+		Upper:      math.Expm1(y + predictionZ*m.stddev),
+		Confidence: predictionConfidence,
+	}
+}
+
+// solve uses gaussian elimination with partial pivoting to solve a x = b.
+func solve(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, errors.New("prediction model is singular")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for row := col + 1; row < n; row++ {
+			factor := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= factor * a[col][k]
+			}
+			b[row] -= factor * b[col]
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < n; k++ {
+			sum -= a[row][k] * x[k]
+		}
+		x[row] = sum / a[row][row]
+	}
+	return x, nil
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"os"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/jgkawell/reddit-api-demo/alert"
 	"github.com/jgkawell/reddit-api-demo/client"
 	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
 
 	"github.com/steady-bytes/draft/pkg/chassis"
+	"go.opentelemetry.io/otel"
 )
 
 type (
@@ -21,18 +25,102 @@ type (
 		// Start begins stat collection and is meant to be run on a background routine.
 		Start()
 		// Stats will return the current top
-		Stats(ctx context.Context, limit int) (links []models.LinkStats, users []models.UserStats, err error)
+		Stats(ctx context.Context, query models.StatsQuery) (stats models.Stats, err error)
+		// Trending will return the posts gaining upvotes the fastest, optionally only those
+		// posted to the given subreddit of a multireddit.
+		Trending(ctx context.Context, subreddit string, limit int) []models.LinkStats
+		// Status reports the current state of stat collection along with an estimate of the
+		// memory used by the tracked data.
+		Status() models.ProcessorStatus
+		// Backfill reports the progress of collecting historical links (if enabled).
+		Backfill() []models.BackfillProgress
+		// Rankings reports where links have ranked on the sampled listings (if enabled),
+		// optionally only links posted to the given subreddit of a multireddit.
+		Rankings(ctx context.Context, subreddit string, limit int) models.Rankings
+		// History returns the score trajectory of the given link (if it is being tracked).
+		History(ctx context.Context, fullname string) (history models.PostHistory, ok bool)
+		// Backtest evaluates the accuracy of score predictions against the collected history,
+		// optionally only the history of the given subreddit of a multireddit.
+		Backtest(ctx context.Context, subreddit string) (backtest models.Backtest, err error)
+		// User returns the stats of the given author (if they have posted to the subreddit).
+		User(ctx context.Context, name string) (stats models.UserStats, ok bool)
+		// Terms returns the most common and emerging terms in post titles over a window,
+		// optionally only from the given subreddit of a multireddit.
+		Terms(ctx context.Context, query models.TermsQuery) models.Terms
+		// Heatmap returns the number of posts and median score by day of the week and hour.
+		Heatmap(ctx context.Context, query models.HeatmapQuery) (heatmap models.Heatmap, err error)
+		// Reposts returns the most recent posts that were flagged as likely reposts, optionally
+		// only those posted to the given subreddit of a multireddit.
+		Reposts(ctx context.Context, subreddit string, limit int) []models.Repost
+		// Removed returns the most recently removed or deleted posts, optionally only those
+		// posted to the given subreddit of a multireddit.
+		Removed(ctx context.Context, subreddit string, limit int) []models.Removal
+		// Anomaly reports whether the volume of new posts is unusual (if anomaly detection is
+		// enabled), optionally for only the given subreddit of a multireddit.
+		Anomaly(ctx context.Context, subreddit string) (anomaly models.Anomaly, ok bool)
 	}
 	processor struct {
-		logger chassis.Logger
-		client client.Client
-		config subredditConfig
+		logger   chassis.Logger
+		client   client.Client
+		enricher Enricher
+		notifier alert.Notifier
+		config   targetConfig
+		filter   linkFilter
 
-		linksMu sync.RWMutex
-		links   map[string]models.Link
+		linksMu  sync.RWMutex
+		links    map[string]models.Link
+		baseline map[string]struct{}
+		// evicted maps the posts dropped by the retention policy to when they were created
+		evicted map[string]time.Time
 
 		usersMu sync.RWMutex
 		users   map[string]user
+
+		statusMu sync.RWMutex
+		status   models.ProcessorStatus
+		failures int
+
+		backfillMu sync.RWMutex
+		backfills  []models.BackfillProgress
+
+		commentsMu       sync.RWMutex
+		comments         map[string]models.Comment
+		commentSentiment map[string]float64
+
+		rankingsMu sync.RWMutex
+		rankings   map[string]*ranking
+		sampled    map[string]time.Time
+
+		historyMu    sync.RWMutex
+		history      map[string][]models.ScorePoint
+		observations map[string]*observation
+		// the prediction model is refit at most once per poll and only when a link's
+		// trajectory has been completed or evicted since the last fit
+		model      *predictionModel
+		modelStale bool
+
+		removalsMu sync.RWMutex
+		removals   map[string]models.Removal
+
+		repostIndex *repostIndex
+		repostsMu   sync.RWMutex
+		reposts     map[string]models.Repost
+
+		authors *authorIndex
+
+		termsMu   sync.RWMutex
+		titles    map[string]titleTerms
+		stopwords map[string]bool
+
+		// volumes are keyed by the target and, for multireddits, each of its subreddits
+		anomalyMu sync.RWMutex
+		volumes   map[string]*volumeBaseline
+		arrived   map[string]time.Time
+		arrivals  map[string]int
+
+		metadataMu sync.RWMutex
+		about      *models.SubredditAboutData
+		metadata   []models.SubredditPoint
 	}
 	user struct {
 		name  string
@@ -40,58 +128,135 @@ type (
 	}
 )
 
-func NewProcessor(logger chassis.Logger, client client.Client, config subredditConfig) Processor {
+var (
+	tracer = otel.Tracer("github.com/jgkawell/reddit-api-demo/controller")
+	meter  = otel.Meter("github.com/jgkawell/reddit-api-demo/controller")
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// NewProcessor creates a Processor for the configured target. The Enricher is optional and
+// should be nil if account enrichment is disabled. Processors that share a repost index detect
+// reposts across each other's posts, a nil index only detects reposts within the target. The
+// same goes for the author index and the subreddits each author is seen posting to.
+func NewProcessor(logger chassis.Logger, client client.Client, enricher Enricher, notifier alert.Notifier, reposts *repostIndex, authors *authorIndex, config targetConfig) Processor {
+	if config.kind == "" {
+		config.kind = targetSubreddit
+	}
+	if reposts == nil {
+		reposts = newRepostIndex()
+	}
+	if authors == nil {
+		authors = newAuthorIndex()
+	}
 	return &processor{
-		logger: logger.WithField("subreddit", config.Name),
-		client: client,
-		config: config,
+		logger:   logger.WithField(string(config.kind), config.Name),
+		client:   client,
+		enricher: enricher,
+		notifier: notifier,
+		config:   config,
+
+		linksMu:  sync.RWMutex{},
+		links:    make(map[string]models.Link),
+		baseline: make(map[string]struct{}),
+		evicted:  make(map[string]time.Time),
+		usersMu:  sync.RWMutex{},
+		users:    make(map[string]user),
+
+		commentsMu:       sync.RWMutex{},
+		comments:         make(map[string]models.Comment),
+		commentSentiment: make(map[string]float64),
+
+		rankingsMu: sync.RWMutex{},
+		rankings:   make(map[string]*ranking),
+		sampled:    make(map[string]time.Time),
+
+		historyMu:    sync.RWMutex{},
+		history:      make(map[string][]models.ScorePoint),
+		observations: make(map[string]*observation),
 
-		linksMu: sync.RWMutex{},
-		links:   make(map[string]models.Link),
-		usersMu: sync.RWMutex{},
-		users:   make(map[string]user),
+		removalsMu: sync.RWMutex{},
+		removals:   make(map[string]models.Removal),
+
+		repostIndex: reposts,
+		repostsMu:   sync.RWMutex{},
+		reposts:     make(map[string]models.Repost),
+
+		authors: authors,
+
+		termsMu:   sync.RWMutex{},
+		titles:    make(map[string]titleTerms),
+		stopwords: newStopwords(config.Terms.Stopwords),
+
+		anomalyMu: sync.RWMutex{},
+		volumes:   make(map[string]*volumeBaseline),
+		arrived:   make(map[string]time.Time),
+		arrivals:  make(map[string]int),
+
+		statusMu: sync.RWMutex{},
+		status: models.ProcessorStatus{
+			Subreddit:  config.key(),
+			State:      models.StateInitializing,
+			StateSince: time.Now(),
+		},
 	}
 }
 
-func (p *processor) Stats(ctx context.Context, limit int) (links []models.LinkStats, users []models.UserStats, err error) {
-	links = []models.LinkStats{}
-	users = []models.UserStats{}
+func (p *processor) Stats(ctx context.Context, query models.StatsQuery) (stats models.Stats, err error) {
+	filter, err := newLinkFilter(query.Filter)
+	if err != nil {
+		return stats, err
+	}
+	filter = filter.inSubreddit(query.Subreddit)
+	if query.MaxSuspicion != nil {
+		filter.excludeAuthors = append(filter.excludeAuthors, p.suspects(*query.MaxSuspicion)...)
+	}
 
-	// collect link stats
-	p.linksMu.RLock()
-	for _, l := range p.links {
-		links = append(links, models.LinkStats{
-			Name:    l.Data.Name,
-			Title:   l.Data.Title,
-			Author:  l.Data.Author,
-			UpVotes: l.Data.Ups,
-		})
+	// users are ranked by the posts that are shown so removed posts only count if included
+	users := filter
+	if !query.IncludeRemoved {
+		users = filter.onlyVisible()
 	}
-	p.linksMu.RUnlock()
-	slices.SortFunc(links, func(a, b models.LinkStats) int {
-		return b.UpVotes - a.UpVotes
-	})
 
-	// collect user stats
-	p.usersMu.RLock()
-	for _, u := range p.users {
-		users = append(users, models.UserStats{
-			Name:      u.name,
-			PostCount: len(u.links),
-		})
+	stats = models.Stats{
+		Subreddit: p.subredditStats(),
+		Posts:     p.linkStats(time.Now(), filter),
+		Users:     p.usersStats(users, query.Limit),
 	}
-	p.usersMu.RUnlock()
-	slices.SortFunc(users, func(a, b models.UserStats) int {
-		return b.PostCount - a.PostCount
-	})
+	stats.Removals = removalStats(stats.Posts)
+	if !query.IncludeRemoved {
+		stats.Posts = visible(stats.Posts)
+	}
+	sortLinks(stats.Posts, query.Sort)
 
-	// apply limit if needed
-	if len(links) > limit {
-		links = links[0:limit]
+	// targets that span subreddits are broken down by where the links were posted
+	if p.config.kind != targetSubreddit || p.config.combined() || query.Group == models.GroupSubreddit {
+		stats.Subreddits = breakdown(stats.Posts)
+	}
+	if query.Group == models.GroupSubreddit {
+		for i, b := range stats.Subreddits {
+			stats.Subreddits[i].Posts = truncate(inSubreddit(stats.Posts, b.Subreddit), query.Limit)
+			stats.Subreddits[i].Users = p.usersStats(users.inSubreddit(b.Subreddit), query.Limit)
+		}
 	}
-	if len(users) > limit {
-		users = users[0:limit]
+
+	// collect comment stats (if enabled)
+	if p.config.Comments.Enabled {
+		stats.Comments, stats.Commenters, stats.CommentersByScore = p.commentStats(query.Subreddit)
 	}
+	stats.Sentiment = sentimentStats(stats.Posts, stats.Comments, query.Limit, time.Now())
+	stats.Media = mediaStats(stats.Posts, query.Window, query.Limit, time.Now())
+	stats.Flairs = flairStats(stats.Posts, query.Limit)
+
+	// apply limit if needed
+	stats.Posts = truncate(stats.Posts, query.Limit)
+	stats.Users = truncate(stats.Users, query.Limit)
+	stats.Comments = truncate(stats.Comments, query.Limit)
+	stats.Commenters = truncate(stats.Commenters, query.Limit)
+	stats.CommentersByScore = truncate(stats.CommentersByScore, query.Limit)
 
 	return
 }
@@ -102,65 +267,207 @@ func (p *processor) Start() {
 		err error
 	)
 
-	if p.config.Start == "" {
-		p.config.Start, err = p.init(ctx)
-		if err != nil {
-			p.logger.WithError(err).Error("failed to find starting link")
-			os.Exit(1)
-		}
+	p.filter, err = newLinkFilter(p.config.Filters)
+	if err != nil {
+		p.logger.WithError(err).Error("failed to compile filters")
+		p.setState(models.StateFailed, err)
+		return
+	}
+
+	// searches always initialize as their results are deduplicated against what was already
+	// matching when the program started rather than paged with a cursor
+	if p.config.Start == "" || p.config.kind == targetSearch {
+		p.config.Start = p.startingLink(ctx)
 		p.logger.WithField("start", p.config.Start).Info("found starting link")
 	} else {
 		p.logger.WithField("start", p.config.Start).Info("using configured starting link")
 	}
 
-	// run stat collection forever as quickly as the rate limit of the Client will allow
+	// combined listings don't have any metadata of their own
+	if p.config.kind == targetSubreddit && !p.config.combined() {
+		go p.startMetadata(ctx)
+	}
+	if p.config.Backfill.Enabled {
+		go p.startBackfill(ctx)
+	}
+	// search results don't include comments
+	if p.config.Comments.Enabled && p.config.kind != targetSearch {
+		go p.startComments(ctx)
+		go p.startCommentScores(ctx)
+	}
+	if len(p.config.Listings.Sources) > 0 {
+		go p.startListings(ctx)
+	}
+	if p.config.Removals.Enabled {
+		go p.startRemovals(ctx)
+	}
+	if p.config.Anomalies.Enabled {
+		go p.startAnomalies(ctx)
+	}
+	go p.startPredictions(ctx)
+	if p.config.Retention.MaxAge > 0 || p.config.Retention.MaxPosts > 0 || p.config.Retention.MaxComments > 0 {
+		go p.startRetention(ctx)
+	}
+
+	// run stat collection forever as quickly as the rate limit of the Client will allow, backing
+	// off whenever the API is failing
+	var estimated time.Time
 	for {
 		p.process(ctx)
+		// the memory estimate walks all of the tracked data so it's only refreshed periodically
+		if time.Since(estimated) >= memoryEstimateInterval {
+			p.updateMemory()
+			estimated = time.Now()
+		}
+		time.Sleep(p.backoff())
 	}
 
 }
 
+func (p *processor) Status() models.ProcessorStatus {
+	p.statusMu.RLock()
+	defer p.statusMu.RUnlock()
+	return p.status
+}
+
+// startingLink initializes the processor, backing off and retrying until the API responds.
+func (p *processor) startingLink(ctx context.Context) string {
+	for {
+		start, err := p.init(ctx)
+		if err == nil {
+			return start
+		}
+		p.logger.WithError(err).Error("failed to find starting link")
+		p.setState(models.StateBackingOff, err)
+		time.Sleep(p.backoff())
+	}
+}
+
 // init gets the latest link to register where to begin data collection
 func (p *processor) init(ctx context.Context) (start string, err error) {
+	ctx, span := tracer.Start(ctx, "processor.init")
+	span.SetAttributes(tracing.SubredditKey.String(p.config.Name))
+	defer func() { tracing.End(span, err) }()
+
 	p.logger.Info("initializing")
 	values := url.Values{
 		"limit": {"1"},
 	}
-	listing, err := p.client.GetLinkListing(ctx, subredditURL(p.config.Name, "new"), values)
+	if p.config.kind == targetSearch {
+		values.Set("limit", "100")
+	}
+	listing, err := p.client.GetLinkListing(ctx, p.listingURL("new", values), values)
 	if err != nil {
 		return
 	}
 	if len(listing.Data.Children) == 0 {
 		return
 	}
+	if p.config.kind == targetSearch {
+		p.linksMu.Lock()
+		for _, link := range listing.Data.Children {
+			p.baseline[link.Data.Name] = struct{}{}
+		}
+		p.linksMu.Unlock()
+	}
 	return listing.Data.Children[0].Data.Name, nil
 }
 
 // process lists the latest links and then processes for new data
 func (p *processor) process(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "processor.poll")
+	span.SetAttributes(
+		tracing.SubredditKey.String(p.config.Name),
+		tracing.CursorKey.String(p.config.Start),
+	)
+	var err error
+	defer func() { tracing.End(span, err) }()
+
 	links, err := p.listLinks(ctx, p.config.Start)
 	if err != nil {
 		p.logger.WithError(err).Error("failed to list links")
+		p.setState(models.StateBackingOff, err)
 		// just return so that process() will be called again
 		return
 	}
+	p.setState(models.StateRunning, nil)
+	p.fitPredictor()
+	if p.config.kind == targetSearch {
+		links = p.dedupe(links)
+	}
+	span.SetAttributes(tracing.ResultCountKey.Int(len(links)))
+	now := time.Now()
+	p.recordArrivals(links, now)
 
 	// process results concurrently
 	for _, link := range links {
+		if !p.filter.match(link.Data) || p.expired(link.Data, now) {
+			continue
+		}
+		if p.config.Alerts && !p.tracked(link.Data.Name) {
+			go p.alertNewLink(ctx, link)
+		}
 		go p.processLink(ctx, link)
 		go p.processUser(ctx, link)
 	}
 }
 
-// listLinks queries the API for the configured subreddit's latest links since the
-// provided "before" link.
+func (p *processor) tracked(fullname string) bool {
+	p.linksMu.RLock()
+	defer p.linksMu.RUnlock()
+	_, ok := p.links[fullname]
+	return ok
+}
+
+// dedupe drops search results that were already matching when the processor initialized along
+// with any repeated within the results.
+func (p *processor) dedupe(links []models.Link) []models.Link {
+	p.linksMu.RLock()
+	defer p.linksMu.RUnlock()
+	seen := map[string]bool{}
+	result := []models.Link{}
+	for _, link := range links {
+		if _, ok := p.baseline[link.Data.Name]; ok || seen[link.Data.Name] {
+			continue
+		}
+		seen[link.Data.Name] = true
+		result = append(result, link)
+	}
+	return result
+}
+
+func (p *processor) alertNewLink(ctx context.Context, link models.Link) {
+	if p.notifier == nil {
+		return
+	}
+	alert := models.Alert{
+		Type:   models.AlertNewPost,
+		Target: p.config.key(),
+		Title:  fmt.Sprintf("u/%s posted in r/%s: %s", link.Data.Author, link.Data.Subreddit, link.Data.Title),
+		URL:    "https://www.reddit.com" + link.Data.Permalink,
+	}
+	if p.config.kind == targetSearch {
+		alert.Type = models.AlertNewMatch
+		alert.Title = fmt.Sprintf("%q matched in r/%s: %s", p.config.Query, link.Data.Subreddit, link.Data.Title)
+	}
+	err := p.notifier.Notify(ctx, alert)
+	if err != nil {
+		p.logger.WithError(err).Warn("failed to send alert")
+	}
+}
+
+// listLinks queries the API for the configured target's latest links since the
+// provided "before" link. Searches ignore the cursor and always return the latest page.
 func (p *processor) listLinks(ctx context.Context, before string) ([]models.Link, error) {
 	p.logger.WithField("before", before).Debug("listing links")
 	values := url.Values{
 		"limit":  {"100"},
 		"before": {before},
 	}
-	listing, err := p.client.GetLinkListing(ctx, subredditURL(p.config.Name, "new"), values)
+	if p.config.kind == targetSearch {
+		values.Del("before")
+	}
+	listing, err := p.client.GetLinkListing(ctx, p.listingURL("new", values), values)
 	if err != nil {
 		return nil, err
 	}
@@ -170,12 +477,40 @@ func (p *processor) listLinks(ctx context.Context, before string) ([]models.Link
 // NOTE: this could be converted to a database call
 func (p *processor) processLink(_ context.Context, link models.Link) {
 	p.linksMu.Lock()
+	previous, seen := p.links[link.Data.Name]
+	if seen && link.Data.Author == models.DeletedAuthor {
+		// deleted posts lose their author so keep the one that was tracked
+		link.Data.Author = previous.Data.Author
+		link.Data.AuthorFullname = previous.Data.AuthorFullname
+	}
 	p.links[link.Data.Name] = link
 	p.linksMu.Unlock()
+
+	if seen {
+		p.updateUser(link)
+	}
+	now := time.Now()
+	if link.Data.Removal() != "" {
+		p.recordRemoval(link, now)
+	}
+	p.recordScore(link, now)
+	p.recordTerms(link, now)
+	if !seen {
+		p.detectRepost(link, now)
+		// only subreddits and multireddits sample the communities that authors post to
+		if p.config.kind == targetSubreddit {
+			p.authors.add(p.config.key(), link.Data)
+		}
+	}
 }
 
 // NOTE: this could be converted to a database call
 func (p *processor) processUser(_ context.Context, link models.Link) {
+	// the post was deleted before it was tracked so there's no author to record
+	if link.Data.Author == models.DeletedAuthor {
+		return
+	}
+
 	// add link to user (creating user if neccesary)
 	p.usersMu.Lock()
 	u, ok := p.users[link.Data.AuthorFullname]
@@ -189,6 +524,228 @@ func (p *processor) processUser(_ context.Context, link models.Link) {
 	u.links[link.Data.Name] = link
 	p.users[link.Data.AuthorFullname] = u
 	p.usersMu.Unlock()
+
+	if p.enricher != nil {
+		p.enricher.Track(link.Data.Author)
+	}
+}
+
+// updateUser replaces the author's copy of an already tracked link so that refreshed scores and
+// removals are reflected in their stats.
+func (p *processor) updateUser(link models.Link) {
+	p.usersMu.Lock()
+	defer p.usersMu.Unlock()
+	u, ok := p.users[link.Data.AuthorFullname]
+	if !ok {
+		return
+	}
+	if _, ok := u.links[link.Data.Name]; ok {
+		u.links[link.Data.Name] = link
+	}
+}
+
+// setState records the outcome of the latest step of stat collection. A nil error marks a
+// successful poll.
+func (p *processor) setState(state models.ProcessorState, err error) {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+	if p.status.State != state {
+		p.status.State = state
+		p.status.StateSince = time.Now()
+	}
+	if err != nil {
+		p.status.LastError = err.Error()
+		p.failures++
+		return
+	}
+	p.status.LastPoll = time.Now()
+	p.failures = 0
+}
+
+// backoff returns how long to wait before the next poll based on the number of consecutive
+// failures (doubling from minBackoff up to maxBackoff).
+func (p *processor) backoff() time.Duration {
+	p.statusMu.RLock()
+	failures := p.failures
+	p.statusMu.RUnlock()
+	if failures == 0 {
+		return 0
+	}
+	wait := minBackoff << min(failures-1, 10)
+	return min(wait, maxBackoff)
+}
+
+func (p *processor) User(_ context.Context, name string) (stats models.UserStats, ok bool) {
+	p.usersMu.RLock()
+	defer p.usersMu.RUnlock()
+	for _, u := range p.users {
+		if u.name == name {
+			return p.userStats(u, linkFilter{}), true
+		}
+	}
+	return stats, false
+}
+
+// userStats converts the user into its stats, including account details if enrichment is
+// enabled. Only the posts that match the filter are counted but the suspicion score is always
+// based on all of the user's posts. The caller must hold usersMu.
+func (p *processor) userStats(u user, filter linkFilter) models.UserStats {
+	stats := p.userCounts(u, filter)
+	if p.enricher != nil {
+		stats.Account = p.enricher.Account(u.name)
+	}
+	stats.Suspicion = p.suspicion(u, stats.Account, time.Now())
+	return stats
+}
+
+// userCounts counts the user's posts and reposts that match the filter, the caller must hold
+// usersMu.
+func (p *processor) userCounts(u user, filter linkFilter) models.UserStats {
+	links := filterLinks(u.links, filter)
+	return models.UserStats{
+		Name:        u.name,
+		PostCount:   len(links),
+		RepostCount: p.repostCount(links),
+	}
+}
+
+// usersStats collects the stats of the top tracked users by their number of posts. Only the
+// posts that match the filter are counted and account details and suspicion scores are only
+// looked up for the users within the limit.
+func (p *processor) usersStats(filter linkFilter, limit int) []models.UserStats {
+	type counted struct {
+		user  user
+		stats models.UserStats
+	}
+	users := []counted{}
+	p.usersMu.RLock()
+	defer p.usersMu.RUnlock()
+	for _, u := range p.users {
+		stats := p.userCounts(u, filter)
+		if stats.PostCount == 0 {
+			continue
+		}
+		users = append(users, counted{user: u, stats: stats})
+	}
+	slices.SortFunc(users, func(a, b counted) int {
+		if b.stats.PostCount != a.stats.PostCount {
+			return b.stats.PostCount - a.stats.PostCount
+		}
+		return strings.Compare(a.stats.Name, b.stats.Name)
+	})
+
+	result := []models.UserStats{}
+	for _, c := range truncate(users, limit) {
+		result = append(result, p.userStats(c.user, filter))
+	}
+	return result
+}
+
+// linkStats collects the stats of every tracked link that matches the filter.
+func (p *processor) linkStats(now time.Time, filter linkFilter) []models.LinkStats {
+	links := []models.LinkStats{}
+	p.linksMu.RLock()
+	p.historyMu.RLock()
+	p.termsMu.RLock()
+	model := p.model
+	for _, l := range p.links {
+		if !filter.match(l.Data) {
+			continue
+		}
+		links = append(links, models.LinkStats{
+			Name:        l.Data.Name,
+			Subreddit:   l.Data.Subreddit,
+			Title:       l.Data.Title,
+			Author:      l.Data.Author,
+			Domain:      l.Data.Domain,
+			Type:        l.Data.Type(),
+			Flair:       l.Data.LinkFlairText,
+			AuthorFlair: l.Data.AuthorFlairText,
+			Created:     p.titles[l.Data.Name].created,
+			UpVotes:     l.Data.Ups,
+			Trending:    trendingScore(l.Data, p.history[l.Data.Name], now),
+			Sentiment:   p.titles[l.Data.Name].sentiment,
+			Removed:     l.Data.Removal(),
+			Prediction:  p.predict(model, l.Data.Name),
+		})
+	}
+	p.termsMu.RUnlock()
+	p.historyMu.RUnlock()
+	p.linksMu.RUnlock()
+	return links
+}
+
+// truncate applies the limit to the given stats.
+func truncate[T any](stats []T, limit int) []T {
+	if len(stats) > limit {
+		return stats[0:limit]
+	}
+	return stats
+}
+
+// inSubreddit filters the stats to the links posted to the subreddit. An empty subreddit
+// matches every link.
+func inSubreddit(links []models.LinkStats, subreddit string) []models.LinkStats {
+	if subreddit == "" {
+		return links
+	}
+	result := []models.LinkStats{}
+	for _, l := range links {
+		if strings.EqualFold(l.Subreddit, subreddit) {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// breakdown groups the links by the subreddit they were posted to.
+func breakdown(links []models.LinkStats) []models.SubredditBreakdown {
+	bySubreddit := map[string]*models.SubredditBreakdown{}
+	for _, l := range links {
+		b, ok := bySubreddit[l.Subreddit]
+		if !ok {
+			b = &models.SubredditBreakdown{Subreddit: l.Subreddit}
+			bySubreddit[l.Subreddit] = b
+		}
+		b.PostCount++
+		b.TotalUpVotes += l.UpVotes
+	}
+
+	result := []models.SubredditBreakdown{}
+	for _, b := range bySubreddit {
+		b.AverageUpVotes = float64(b.TotalUpVotes) / float64(b.PostCount)
+		result = append(result, *b)
+	}
+	slices.SortFunc(result, func(a, b models.SubredditBreakdown) int {
+		if b.PostCount != a.PostCount {
+			return b.PostCount - a.PostCount
+		}
+		return strings.Compare(a.Subreddit, b.Subreddit)
+	})
+	return result
+}
+
+// listingURL builds the URL of the given listing for the target, setting any extra values the
+// target's listings need.
+func (p *processor) listingURL(sort string, values url.Values) string {
+	switch p.config.kind {
+	case targetUser:
+		if sort == "comments" {
+			return userURL(p.config.Name, "comments")
+		}
+		values.Set("sort", sort)
+		return userURL(p.config.Name, "submitted")
+	case targetSearch:
+		values.Set("q", p.config.Query)
+		values.Set("sort", sort)
+		if p.config.Subreddit != "" {
+			values.Set("restrict_sr", "true")
+			return subredditURL(p.config.Subreddit, "search")
+		}
+		return "https://oauth.reddit.com/search"
+	default:
+		return subredditURL(p.config.Name, sort)
+	}
 }
 
 func subredditURL(subreddit string, sort string) string {
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
+)
+
+type (
+	// removalsConfig enables refreshing tracked links by their fullname so that removed and
+	// deleted posts (which drop out of the listings) are noticed. Links older than MaxAge are no
+	// longer refreshed.
+	removalsConfig struct {
+		Enabled  bool
+		Interval time.Duration
+		MaxAge   time.Duration
+	}
+)
+
+const (
+	defaultRemovalsInterval = 5 * time.Minute
+	defaultRemovalsMaxAge   = 24 * time.Hour
+	// the maximum number of fullnames that can be looked up at once
+	infoBatchSize = 100
+	infoURL       = "https://oauth.reddit.com/api/info"
+)
+
+// startRemovals refreshes the tracked links forever and is meant to be run on a background
+// routine.
+func (p *processor) startRemovals(ctx context.Context) {
+	interval := p.config.Removals.Interval
+	if interval <= 0 {
+		interval = defaultRemovalsInterval
+	}
+	for {
+		err := p.refreshLinks(ctx, time.Now())
+		if err != nil {
+			p.logger.WithError(err).Warn("failed to refresh links")
+		}
+		time.Sleep(interval)
+	}
+}
+
+// refreshLinks looks up every tracked link younger than the max age by its fullname and
+// processes the latest version of it, which records any removal.
+func (p *processor) refreshLinks(ctx context.Context, now time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "processor.refresh")
+	span.SetAttributes(tracing.SubredditKey.String(p.config.Name))
+	defer func() { tracing.End(span, err) }()
+
+	maxAge := p.config.Removals.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultRemovalsMaxAge
+	}
+	names := []string{}
+	p.linksMu.RLock()
+	for name, l := range p.links {
+		if now.Sub(l.Data.Created()) <= maxAge && l.Data.Removal() == "" {
+			names = append(names, name)
+		}
+	}
+	p.linksMu.RUnlock()
+	span.SetAttributes(tracing.ResultCountKey.Int(len(names)))
+
+	return p.lookupLinks(ctx, names, now)
+}
+
+// lookupLinks fetches the latest version of the named links by their fullnames and processes
+// them.
+func (p *processor) lookupLinks(ctx context.Context, names []string, now time.Time) error {
+	for batch := range slices.Chunk(names, infoBatchSize) {
+		values := url.Values{
+			"id": {strings.Join(batch, ",")},
+		}
+		listing, err := p.client.GetLinkListing(ctx, infoURL, values)
+		if err != nil {
+			return err
+		}
+		for _, link := range listing.Data.Children {
+			// the link may have been evicted since the batch was collected
+			if p.expired(link.Data, now) {
+				continue
+			}
+			p.processLink(ctx, link)
+		}
+	}
+	return nil
+}
+
+// Removed returns the most recently removed or deleted posts, optionally only those posted to
+// the given subreddit.
+func (p *processor) Removed(_ context.Context, subreddit string, limit int) []models.Removal {
+	p.removalsMu.RLock()
+	removals := []models.Removal{}
+	for _, r := range p.removals {
+		if subreddit != "" && !strings.EqualFold(r.Subreddit, subreddit) {
+			continue
+		}
+		removals = append(removals, r)
+	}
+	p.removalsMu.RUnlock()
+	slices.SortFunc(removals, func(a, b models.Removal) int {
+		return b.DetectedAt.Compare(a.DetectedAt)
+	})
+	return truncate(removals, limit)
+}
+
+// recordRemoval records when the link was first noticed to be removed or deleted.
+func (p *processor) recordRemoval(link models.Link, now time.Time) {
+	p.historyMu.RLock()
+	points := p.history[link.Data.Name]
+	lastSeen := time.Time{}
+	if len(points) > 0 {
+		lastSeen = points[len(points)-1].Time
+	}
+	p.historyMu.RUnlock()
+
+	p.removalsMu.Lock()
+	defer p.removalsMu.Unlock()
+	if _, ok := p.removals[link.Data.Name]; ok {
+		return
+	}
+	p.removals[link.Data.Name] = models.Removal{
+		Name:       link.Data.Name,
+		Subreddit:  link.Data.Subreddit,
+		Title:      link.Data.Title,
+		Author:     link.Data.Author,
+		Reason:     link.Data.Removal(),
+		UpVotes:    link.Data.Ups,
+		Created:    link.Data.Created(),
+		LastSeen:   lastSeen,
+		DetectedAt: now,
+	}
+	p.logger.WithField("link", link.Data.Name).WithField("reason", link.Data.Removal()).Debug("detected removal")
+}
+
+// removalStats counts the removed and deleted posts among the tracked posts.
+func removalStats(posts []models.LinkStats) *models.RemovalStats {
+	stats := &models.RemovalStats{
+		Posts:   len(posts),
+		Reasons: []models.RemovalReason{},
+	}
+	reasons := map[string]int{}
+	for _, l := range posts {
+		switch l.Removed {
+		case "":
+			continue
+		case models.RemovalDeleted:
+			stats.Deleted++
+		default:
+			stats.Removed++
+		}
+		reasons[l.Removed]++
+	}
+	if stats.Posts > 0 {
+		stats.RemovalRate = float64(stats.Removed+stats.Deleted) / float64(stats.Posts)
+	}
+	for reason, count := range reasons {
+		stats.Reasons = append(stats.Reasons, models.RemovalReason{Reason: reason, Count: count})
+	}
+	slices.SortFunc(stats.Reasons, func(a, b models.RemovalReason) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Reason, b.Reason))
+	})
+	return stats
+}
+
+// visible leaves out the posts that were removed or deleted.
+func visible(posts []models.LinkStats) []models.LinkStats {
+	return slices.DeleteFunc(posts, func(l models.LinkStats) bool {
+		return l.Removed != ""
+	})
+}
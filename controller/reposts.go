@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+type (
+	// repostIndex holds the title fingerprint and URL of every post tracked by any Processor so
+	// that reposts can be detected across subreddits. Posts are keyed by their fullname so that a
+	// post tracked by more than one target is only indexed once.
+	repostIndex struct {
+		mu    sync.RWMutex
+		posts map[string]*indexedPost
+		urls  map[string]*indexedPost
+	}
+	// indexedPost is an indexed post along with the targets that are tracking it
+	indexedPost struct {
+		name      string
+		subreddit string
+		title     string
+		created   time.Time
+		simhash   uint64
+		hashed    bool
+		link      string
+		targets   map[string]bool
+	}
+)
+
+const (
+	// titles with fewer terms than this are too generic to be compared
+	minRepostTerms = 3
+	// titles at least this similar (the fraction of matching SimHash bits) are reposts
+	repostSimilarity = 0.9
+)
+
+func newRepostIndex() *repostIndex {
+	return &repostIndex{
+		mu:    sync.RWMutex{},
+		posts: map[string]*indexedPost{},
+		urls:  map[string]*indexedPost{},
+	}
+}
+
+// Reposts returns the most recent posts that were flagged as likely reposts.
+func (p *processor) Reposts(_ context.Context, subreddit string, limit int) []models.Repost {
+	p.repostsMu.RLock()
+	reposts := []models.Repost{}
+	for _, r := range p.reposts {
+		if subreddit != "" && !strings.EqualFold(r.Subreddit, subreddit) {
+			continue
+		}
+		reposts = append(reposts, r)
+	}
+	p.repostsMu.RUnlock()
+	slices.SortFunc(reposts, func(a, b models.Repost) int {
+		return b.Created.Compare(a.Created)
+	})
+	return truncate(reposts, limit)
+}
+
+// detectRepost adds the link to the shared repost index, recording it if it is a likely repost.
+func (p *processor) detectRepost(link models.Link, now time.Time) {
+	created := link.Data.Created()
+	if link.Data.CreatedUTC == 0 {
+		created = now
+	}
+	post := indexedPost{
+		name:      link.Data.Name,
+		subreddit: link.Data.Subreddit,
+		title:     link.Data.Title,
+		created:   created,
+	}
+	terms, bigrams := tokenize(link.Data.Title, p.stopwords)
+	if len(terms) >= minRepostTerms {
+		post.simhash = simhash(slices.Concat(terms, bigrams))
+		post.hashed = true
+	}
+
+	repost, ok := p.repostIndex.add(p.config.key(), post, normalizeURL(link.Data))
+	if !ok {
+		return
+	}
+	repost.Title = link.Data.Title
+	repost.Author = link.Data.Author
+	p.repostsMu.Lock()
+	p.reposts[link.Data.Name] = repost
+	p.repostsMu.Unlock()
+	p.logger.WithField("link", link.Data.Name).WithField("original", repost.Original).Debug("detected repost")
+}
+
+// repostCount is the number of the links that were flagged as reposts.
+func (p *processor) repostCount(links map[string]models.Link) (count int) {
+	p.repostsMu.RLock()
+	defer p.repostsMu.RUnlock()
+	for name := range links {
+		if _, ok := p.reposts[name]; ok {
+			count++
+		}
+	}
+	return
+}
+
+// add indexes the post on behalf of the target, returning the most similar older post if the
+// new one is a likely repost of it. Posts that link to the same URL are always reposts. Posts
+// that are indexed out of order (e.g. by a backfill) are only compared to the posts that were
+// indexed before them. Equally similar posts are broken by the oldest.
+func (i *repostIndex) add(target string, post indexedPost, link string) (repost models.Repost, ok bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	indexed, tracked := i.posts[post.name]
+	if !tracked {
+		post.link = link
+		post.targets = map[string]bool{}
+		indexed = &post
+		i.posts[post.name] = indexed
+	}
+	indexed.targets[target] = true
+
+	var original *indexedPost
+	if link != "" {
+		if original, ok = i.urls[link]; ok && original.created.Before(post.created) {
+			repost.SameURL = true
+			repost.Similarity = 1
+		} else {
+			ok = false
+			i.urls[link] = indexed
+		}
+	}
+	if !ok && post.hashed {
+		for _, other := range i.posts {
+			if !other.hashed || !other.created.Before(post.created) {
+				continue
+			}
+			similarity := 1 - float64(bits.OnesCount64(other.simhash^post.simhash))/64
+			if similarity < repostSimilarity || similarity < repost.Similarity {
+				continue
+			}
+			if similarity == repost.Similarity && cmp.Or(other.created.Compare(original.created), strings.Compare(other.name, original.name)) > 0 {
+				continue
+			}
+			original, repost.Similarity, ok = other, similarity, true
+		}
+	}
+	if !ok {
+		return repost, false
+	}
+
+	repost.Name = post.name
+	repost.Subreddit = post.subreddit
+	repost.Created = post.created
+	repost.Original = original.name
+	repost.OriginalSubreddit = original.subreddit
+	repost.OriginalTitle = original.title
+	return repost, true
+}
+
+// simhash fingerprints the features so that similar sets of features have fingerprints that
+// differ in only a few bits.
+func simhash(features []string) uint64 {
+	weights := [64]int{}
+	for _, f := range features {
+		h := fnv.New64a()
+		h.Write([]byte(f))
+		sum := h.Sum64()
+		for b := range weights {
+			if sum&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var result uint64
+	for b, w := range weights {
+		if w > 0 {
+			result |= 1 << b
+		}
+	}
+	return result
+}
+
+// normalizeURL returns the URL the post links to without its scheme, "www." prefix, fragment
+// or trailing slash. Self posts don't link anywhere and return an empty string.
+func normalizeURL(link models.LinkData) string {
+	if link.IsSelf || link.URL == "" {
+		return ""
+	}
+	u, err := url.Parse(link.URL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	result := host + strings.TrimSuffix(u.Path, "/")
+	if u.RawQuery != "" {
+		result += "?" + u.RawQuery
+	}
+	return result
+}
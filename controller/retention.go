@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
+)
+
+type (
+	// retentionConfig bounds how much tracked data is kept in memory. Posts and comments created
+	// more than MaxAge ago are evicted along with the oldest posts beyond MaxPosts and the oldest
+	// comments beyond MaxComments. Zero values keep everything.
+	retentionConfig struct {
+		MaxAge      time.Duration
+		MaxPosts    int
+		MaxComments int
+		Interval    time.Duration
+	}
+)
+
+const (
+	defaultRetentionInterval = 5 * time.Minute
+	// how long evicted posts are remembered (from when they were created) and rankings are kept
+	// (from when they were last seen) if there is no max age, by then they have long dropped out
+	// of the listings that are polled
+	defaultRetentionAge = 7 * 24 * time.Hour
+	// how often the memory estimate reported in the status is refreshed
+	memoryEstimateInterval = time.Minute
+)
+
+// startRetention evicts expired data forever and is meant to be run on a background routine.
+func (p *processor) startRetention(ctx context.Context) {
+	interval := p.config.Retention.Interval
+	if interval <= 0 {
+		interval = defaultRetentionInterval
+	}
+	for {
+		time.Sleep(interval)
+		p.evict(ctx, time.Now())
+	}
+}
+
+// evict drops the posts that are past the retention policy along with everything recorded
+// about them: their authors' post counts, score history, title terms, rankings, removals,
+// reposts, comments and their entries in the shared repost and author indexes. Authors left
+// without any posts are dropped too. Evicted posts are remembered so that they aren't tracked
+// again when a poll, refresh or backfill returns them. Rankings and comments are also evicted
+// on their own since the sampled listings and comment stream include posts that aren't tracked.
+func (p *processor) evict(ctx context.Context, now time.Time) (evicted int) {
+	_, span := tracer.Start(ctx, "processor.evict")
+	span.SetAttributes(tracing.SubredditKey.String(p.config.Name))
+	defer func() {
+		span.SetAttributes(tracing.ResultCountKey.Int(evicted))
+		span.End()
+	}()
+
+	retention := p.config.Retention
+	names := map[string]bool{}
+	p.linksMu.Lock()
+	kept := []models.LinkData{}
+	for _, l := range p.links {
+		if retention.MaxAge > 0 && l.Data.CreatedUTC > 0 && now.Sub(l.Data.Created()) > retention.MaxAge {
+			names[l.Data.Name] = true
+			continue
+		}
+		kept = append(kept, l.Data)
+	}
+	if retention.MaxPosts > 0 && len(kept) > retention.MaxPosts {
+		// newest first so the oldest posts are the ones past the limit
+		slices.SortFunc(kept, func(a, b models.LinkData) int {
+			return cmp.Or(cmpDesc(a.CreatedUTC, b.CreatedUTC), strings.Compare(a.Name, b.Name))
+		})
+		for _, l := range kept[retention.MaxPosts:] {
+			names[l.Name] = true
+		}
+	}
+	for name := range names {
+		p.evicted[name] = p.links[name].Data.Created()
+		delete(p.links, name)
+	}
+	// posts are only remembered for as long as they could otherwise be tracked again
+	for name, created := range p.evicted {
+		if now.Sub(created) > p.retentionAge() {
+			delete(p.evicted, name)
+		}
+	}
+	p.linksMu.Unlock()
+
+	p.evictRankings(names, now)
+	p.evictComments(names, now)
+	if len(names) == 0 {
+		p.updateMemory()
+		return 0
+	}
+
+	p.usersMu.Lock()
+	for key, u := range p.users {
+		for name := range u.links {
+			if names[name] {
+				delete(u.links, name)
+			}
+		}
+		if len(u.links) == 0 {
+			delete(p.users, key)
+		}
+	}
+	p.usersMu.Unlock()
+
+	p.historyMu.Lock()
+	p.termsMu.Lock()
+	p.removalsMu.Lock()
+	p.repostsMu.Lock()
+	for name := range names {
+		delete(p.history, name)
+		if o, ok := p.observations[name]; ok && o.final != nil {
+			p.modelStale = true
+		}
+		delete(p.observations, name)
+		delete(p.titles, name)
+		delete(p.removals, name)
+		delete(p.reposts, name)
+	}
+	p.repostsMu.Unlock()
+	p.removalsMu.Unlock()
+	p.termsMu.Unlock()
+	p.historyMu.Unlock()
+
+	p.repostIndex.remove(p.config.key(), names)
+	p.authors.remove(p.config.key(), names)
+
+	p.updateMemory()
+	p.logger.WithField("links", len(names)).Debug("evicted links")
+	return len(names)
+}
+
+// evictRankings drops the rankings of the evicted posts along with those that haven't been seen
+// on any sampled listing for longer than the retention age.
+func (p *processor) evictRankings(names map[string]bool, now time.Time) {
+	p.rankingsMu.Lock()
+	defer p.rankingsMu.Unlock()
+	for name, r := range p.rankings {
+		var lastSeen time.Time
+		for _, lr := range r.listings {
+			if lr.lastSeen.After(lastSeen) {
+				lastSeen = lr.lastSeen
+			}
+		}
+		if names[name] || now.Sub(lastSeen) > p.retentionAge() {
+			delete(p.rankings, name)
+		}
+	}
+}
+
+// evictComments drops the comments on the evicted posts along with the comments created more
+// than MaxAge ago and the oldest comments beyond MaxComments.
+func (p *processor) evictComments(names map[string]bool, now time.Time) {
+	retention := p.config.Retention
+	p.commentsMu.Lock()
+	defer p.commentsMu.Unlock()
+	kept := []models.CommentData{}
+	for name, c := range p.comments {
+		if names[c.Data.LinkID] || (retention.MaxAge > 0 && c.Data.CreatedUTC > 0 && now.Sub(c.Data.Created()) > retention.MaxAge) {
+			delete(p.comments, name)
+			delete(p.commentSentiment, name)
+			continue
+		}
+		kept = append(kept, c.Data)
+	}
+	if retention.MaxComments <= 0 || len(kept) <= retention.MaxComments {
+		return
+	}
+	// newest first so the oldest comments are the ones past the limit
+	slices.SortFunc(kept, func(a, b models.CommentData) int {
+		return cmp.Or(cmpDesc(a.CreatedUTC, b.CreatedUTC), strings.Compare(a.Name, b.Name))
+	})
+	for _, c := range kept[retention.MaxComments:] {
+		delete(p.comments, c.Name)
+		delete(p.commentSentiment, c.Name)
+	}
+}
+
+// expired reports whether the link was evicted by the retention policy or would be evicted for
+// its age straight away, in which case it shouldn't be tracked again.
+func (p *processor) expired(link models.LinkData, now time.Time) bool {
+	retention := p.config.Retention
+	if retention.MaxAge <= 0 && retention.MaxPosts <= 0 {
+		return false
+	}
+	if retention.MaxAge > 0 && link.CreatedUTC > 0 && now.Sub(link.Created()) > retention.MaxAge {
+		return true
+	}
+	p.linksMu.RLock()
+	defer p.linksMu.RUnlock()
+	_, ok := p.evicted[link.Name]
+	return ok
+}
+
+// retentionAge is how long evicted posts are remembered for after they were created and how
+// long rankings are kept for after they were last seen.
+func (p *processor) retentionAge() time.Duration {
+	if p.config.Retention.MaxAge > 0 {
+		return p.config.Retention.MaxAge
+	}
+	return defaultRetentionAge
+}
+
+// updateMemory refreshes the tracked data counts and memory estimate reported by Status.
+func (p *processor) updateMemory() {
+	links, users, bytes := p.memoryEstimate()
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+	p.status.Links = links
+	p.status.Users = users
+	p.status.MemoryBytes = bytes
+}
+
+// remove releases the target's references to the named posts. Posts are only dropped from the
+// index (including the URL they were indexed by) once no target is tracking them.
+func (i *repostIndex) remove(target string, names map[string]bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for name := range names {
+		post, ok := i.posts[name]
+		if !ok {
+			continue
+		}
+		delete(post.targets, target)
+		if len(post.targets) > 0 {
+			continue
+		}
+		delete(i.posts, name)
+		if i.urls[post.link] == post {
+			delete(i.urls, post.link)
+		}
+	}
+}
+
+// memoryEstimate approximates the number of bytes of tracked data held by the processor. Only
+// the largest structures are counted: posts (once for the subreddit and once for their
+// author), score history, title terms and comments.
+func (p *processor) memoryEstimate() (links int, users int, bytes int64) {
+	linkSize := int64(unsafe.Sizeof(models.Link{}))
+	pointSize := int64(unsafe.Sizeof(models.ScorePoint{}))
+	commentSize := int64(unsafe.Sizeof(models.Comment{}))
+
+	p.linksMu.RLock()
+	links = len(p.links)
+	for _, l := range p.links {
+		bytes += linkSize + linkStrings(l.Data)
+	}
+	p.linksMu.RUnlock()
+
+	p.usersMu.RLock()
+	users = len(p.users)
+	for _, u := range p.users {
+		bytes += int64(len(u.name)) + int64(len(u.links))*linkSize
+	}
+	p.usersMu.RUnlock()
+
+	p.historyMu.RLock()
+	for _, points := range p.history {
+		bytes += int64(cap(points)) * pointSize
+	}
+	p.historyMu.RUnlock()
+
+	p.termsMu.RLock()
+	for _, t := range p.titles {
+		for _, term := range slices.Concat(t.terms, t.bigrams) {
+			bytes += int64(len(term)) + int64(unsafe.Sizeof(term))
+		}
+	}
+	p.termsMu.RUnlock()
+
+	p.commentsMu.RLock()
+	for _, c := range p.comments {
+		bytes += commentSize + int64(len(c.Data.Name)+len(c.Data.Author)+len(c.Data.Body)+len(c.Data.LinkID))
+	}
+	p.commentsMu.RUnlock()
+
+	return links, users, bytes
+}
+
+// linkStrings is the number of bytes used by the link's text fields.
+func linkStrings(l models.LinkData) int64 {
+	total := 0
+	for _, s := range []string{
+		l.Name, l.Subreddit, l.AuthorFullname, l.Title, l.Author, l.Permalink, l.URL, l.Domain,
+		l.LinkFlairText, l.AuthorFlairText, l.PostHint, l.Selftext, l.RemovedBy,
+	} {
+		total += len(s)
+	}
+	return int64(total)
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	_ "embed"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+const (
+	// sentiment over time is averaged into buckets over the most recent window
+	sentimentBucket = time.Hour
+	sentimentWindow = 7 * 24 * time.Hour
+	// negations flip the valence of the words that follow them within this many words
+	negationScope = 3
+	// normalizes the summed valence into (-1, 1), the larger it is the more words are needed
+	// for an extreme score
+	sentimentAlpha = 15
+)
+
+//go:embed sentiment.txt
+var lexiconFile string
+
+// lexicon is the valence of each word, parsed from the embedded lexicon file
+var lexicon = parseLexicon(lexiconFile)
+
+var (
+	negations    = []string{"not", "no", "never", "none", "nobody", "nothing", "neither", "nor", "cannot", "without"}
+	intensifiers = map[string]float64{"very": 1.5, "really": 1.5, "so": 1.3, "extremely": 2, "super": 1.5, "totally": 1.5, "absolutely": 1.5, "incredibly": 1.5, "slightly": 0.5, "somewhat": 0.7}
+)
+
+// sentiment scores the text from -1 (most negative) to 1 (most positive). Each word's valence
+// is looked up in the lexicon, scaled by any intensifier before it and flipped if it follows a
+// negation, then the sum is normalized.
+func sentiment(text string) float64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’'
+	})
+
+	sum := 0.0
+	negated := 0
+	scale := 1.0
+	for _, word := range words {
+		word = strings.NewReplacer("’", "'").Replace(word)
+		if slices.Contains(negations, word) || strings.HasSuffix(word, "n't") {
+			negated = negationScope
+			continue
+		}
+		if s, ok := intensifiers[word]; ok {
+			scale = s
+			continue
+		}
+		if valence, ok := lexicon[word]; ok {
+			if negated > 0 {
+				valence = -valence
+			}
+			sum += valence * scale
+		}
+		scale = 1
+		negated = max(negated-1, 0)
+	}
+
+	return sum / math.Sqrt(sum*sum+sentimentAlpha)
+}
+
+func parseLexicon(file string) map[string]float64 {
+	result := map[string]float64{}
+	for _, line := range strings.Split(file, "\n") {
+		word, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok || strings.HasPrefix(word, "#") {
+			continue
+		}
+		valence, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		result[word] = valence
+	}
+	return result
+}
+
+// sentimentStats summarizes the sentiment of the posts (and comments if they are enabled) with
+// the most positive and most negative posts and the average sentiment of each bucket over the
+// sentiment window.
+func sentimentStats(posts []models.LinkStats, comments []models.CommentStats, limit int, now time.Time) *models.SentimentStats {
+	stats := &models.SentimentStats{
+		Positive: []models.LinkStats{},
+		Negative: []models.LinkStats{},
+		Points:   []models.SentimentPoint{},
+	}
+
+	start := now.Add(-sentimentWindow).Truncate(sentimentBucket)
+	points := map[time.Time]*models.SentimentPoint{}
+	bucket := func(created time.Time) *models.SentimentPoint {
+		if created.Before(start) {
+			return nil
+		}
+		t := created.Truncate(sentimentBucket)
+		point, ok := points[t]
+		if !ok {
+			point = &models.SentimentPoint{Time: t}
+			points[t] = point
+		}
+		return point
+	}
+
+	for _, l := range posts {
+		stats.Average += l.Sentiment
+		if l.Sentiment > 0 {
+			stats.Positive = append(stats.Positive, l)
+		}
+		if l.Sentiment < 0 {
+			stats.Negative = append(stats.Negative, l)
+		}
+		if point := bucket(l.Created); point != nil {
+			point.Posts++
+			point.Average += l.Sentiment
+		}
+	}
+	if len(posts) > 0 {
+		stats.Average /= float64(len(posts))
+	}
+	for _, c := range comments {
+		stats.CommentAverage += c.Sentiment
+		if point := bucket(c.Created); point != nil {
+			point.Comments++
+			point.CommentAverage += c.Sentiment
+		}
+	}
+	if len(comments) > 0 {
+		stats.CommentAverage /= float64(len(comments))
+	}
+
+	for _, point := range points {
+		if point.Posts > 0 {
+			point.Average /= float64(point.Posts)
+		}
+		if point.Comments > 0 {
+			point.CommentAverage /= float64(point.Comments)
+		}
+		stats.Points = append(stats.Points, *point)
+	}
+	slices.SortFunc(stats.Points, func(a, b models.SentimentPoint) int {
+		return a.Time.Compare(b.Time)
+	})
+	slices.SortFunc(stats.Positive, func(a, b models.LinkStats) int {
+		return cmpDesc(a.Sentiment, b.Sentiment)
+	})
+	slices.SortFunc(stats.Negative, func(a, b models.LinkStats) int {
+		return cmpDesc(b.Sentiment, a.Sentiment)
+	})
+	stats.Positive = truncate(stats.Positive, limit)
+	stats.Negative = truncate(stats.Negative, limit)
+
+	return stats
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+type (
+	// signal is a single spam heuristic scored from 0 (normal) to 1 (bot-like)
+	signal struct {
+		weight float64
+		score  float64
+	}
+)
+
+const (
+	// authors need at least this many tracked posts before their posting patterns are judged
+	minSuspicionPosts = 3
+	// a median gap between posts of fastCadence or less is bot-like, slowCadence or more is normal
+	fastCadence = 5 * time.Minute
+	slowCadence = 2 * time.Hour
+	// accounts younger than newAccountAge are bot-like, younger than youngAccountAge somewhat
+	newAccountAge   = 7 * 24 * time.Hour
+	youngAccountAge = 30 * 24 * time.Hour
+	// posting to floodSubreddits or more tracked subreddits is bot-like
+	floodSubreddits = 5
+)
+
+var digits = regexp.MustCompile(`\d+`)
+
+// suspicion combines several heuristics into a score from 0 to 1 of how likely the author is a
+// spam bot: how quickly they post, how often they link to the same domain, how often their
+// titles follow the same template, how new their account is (if enrichment is enabled) and how
+// many tracked subreddits they post to. Heuristics that can't be measured are left out of the
+// weighting. The caller must hold usersMu.
+func (p *processor) suspicion(u user, account *models.Account, now time.Time) float64 {
+	signals := []signal{}
+	if len(u.links) >= minSuspicionPosts {
+		links := []models.LinkData{}
+		for _, l := range u.links {
+			links = append(links, l.Data)
+		}
+		slices.SortFunc(links, func(a, b models.LinkData) int {
+			return a.Created().Compare(b.Created())
+		})
+
+		signals = append(signals,
+			signal{weight: 0.3, score: cadenceSignal(links)},
+			signal{weight: 0.2, score: templateSignal(links)},
+			signal{weight: 0.15, score: floodSignal(p.authors.subreddits(u.name))},
+		)
+		if s, ok := domainSignal(links); ok {
+			signals = append(signals, signal{weight: 0.2, score: s})
+		}
+	}
+	if account != nil {
+		signals = append(signals, signal{weight: 0.15, score: accountSignal(*account, now)})
+	}
+
+	total, weights := 0.0, 0.0
+	for _, s := range signals {
+		total += s.weight * s.score
+		weights += s.weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return total / weights
+}
+
+// suspects returns the lower case names of the authors whose suspicion is above the threshold.
+func (p *processor) suspects(threshold float64) []string {
+	now := time.Now()
+	result := []string{}
+	p.usersMu.RLock()
+	defer p.usersMu.RUnlock()
+	for _, u := range p.users {
+		var account *models.Account
+		if p.enricher != nil {
+			account = p.enricher.Account(u.name)
+		}
+		if p.suspicion(u, account, now) > threshold {
+			result = append(result, strings.ToLower(u.name))
+		}
+	}
+	return result
+}
+
+// cadenceSignal scores the median gap between posts on a log scale between the fast and slow
+// cadences.
+func cadenceSignal(links []models.LinkData) float64 {
+	gaps := []time.Duration{}
+	for i := 1; i < len(links); i++ {
+		gaps = append(gaps, links[i].Created().Sub(links[i-1].Created()))
+	}
+	slices.Sort(gaps)
+	median := max(gaps[len(gaps)/2], time.Second)
+	score := 1 - math.Log(float64(median)/float64(fastCadence))/math.Log(float64(slowCadence)/float64(fastCadence))
+	return clamp(score)
+}
+
+// domainSignal scores how much more than half of the author's link posts go to their most common
+// domain. Authors with too few link posts aren't scored.
+func domainSignal(links []models.LinkData) (float64, bool) {
+	domains := map[string]int{}
+	total := 0
+	for _, l := range links {
+		if l.IsSelf || l.Domain == "" {
+			continue
+		}
+		domains[strings.ToLower(l.Domain)]++
+		total++
+	}
+	if total < minSuspicionPosts {
+		return 0, false
+	}
+	most := 0
+	for _, count := range domains {
+		most = max(most, count)
+	}
+	return clamp((float64(most)/float64(total) - 0.5) * 2), true
+}
+
+// templateSignal is the share of the author's titles that are the same as another of their
+// titles once numbers are ignored.
+func templateSignal(links []models.LinkData) float64 {
+	templates := map[string]int{}
+	for _, l := range links {
+		templates[titleTemplate(l.Title)]++
+	}
+	repeated := 0
+	for _, count := range templates {
+		if count > 1 {
+			repeated += count
+		}
+	}
+	return float64(repeated) / float64(len(links))
+}
+
+// floodSignal scores the number of tracked subreddits the author posts to.
+func floodSignal(subreddits int) float64 {
+	return clamp(float64(subreddits-1) / float64(floodSubreddits-1))
+}
+
+func accountSignal(account models.Account, now time.Time) float64 {
+	switch age := now.Sub(account.Created); {
+	case account.Suspended:
+		return 1
+	case age < newAccountAge:
+		return 1
+	case age < youngAccountAge:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+func titleTemplate(title string) string {
+	return strings.Join(strings.Fields(digits.ReplaceAllString(strings.ToLower(title), "#")), " ")
+}
+
+func clamp(score float64) float64 {
+	return min(max(score, 0), 1)
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+type (
+	// termsConfig adds to the built in list of stopwords that are left out of title terms.
+	termsConfig struct {
+		Stopwords []string
+	}
+	// titleTerms are the distinct terms, bigrams and sentiment of a single post's title
+	titleTerms struct {
+		subreddit string
+		created   time.Time
+		terms     []string
+		bigrams   []string
+		sentiment float64
+	}
+	// termCounts are the number of posts each term and bigram appeared in over a window
+	termCounts struct {
+		posts   int
+		terms   map[string]int
+		bigrams map[string]int
+	}
+)
+
+const (
+	defaultTermsWindow = 24 * time.Hour
+	// terms must appear in at least this many posts in the current window to be emerging
+	minEmergingCount = 2
+)
+
+// stopwords are common English words that say nothing about the topic of a post
+var stopwords = []string{
+	"a", "about", "after", "again", "all", "am", "an", "and", "any", "are", "as", "at", "be",
+	"been", "before", "being", "but", "by", "can", "could", "did", "do", "does", "doing", "dont",
+	"for", "from", "get", "got", "had", "has", "have", "having", "he", "her", "here", "him",
+	"his", "how", "if", "im", "in", "into", "is", "it", "its", "ive", "just", "me", "more",
+	"most", "my", "no", "not", "now", "of", "off", "on", "one", "only", "or", "other", "our",
+	"out", "over", "she", "so", "some", "than", "that", "the", "their", "them", "then",
+	"there", "these", "they", "this", "those", "to", "too", "up", "us", "very", "was", "we",
+	"were", "what", "when", "where", "which", "who", "why", "will", "with", "would", "you",
+	"your",
+}
+
+// Terms returns the terms and bigrams that appeared in the most post titles over the window
+// along with those that grew the most compared to the previous window.
+func (p *processor) Terms(_ context.Context, query models.TermsQuery) models.Terms {
+	window := query.Window
+	if window <= 0 {
+		window = defaultTermsWindow
+	}
+	now := time.Now()
+
+	current, previous := newTermCounts(), newTermCounts()
+	p.termsMu.RLock()
+	for _, t := range p.titles {
+		if query.Subreddit != "" && !strings.EqualFold(t.subreddit, query.Subreddit) {
+			continue
+		}
+		age := now.Sub(t.created)
+		switch {
+		case age < window:
+			current.add(t)
+		case age < 2*window:
+			previous.add(t)
+		}
+	}
+	p.termsMu.RUnlock()
+
+	terms := termStats(current.terms, previous.terms)
+	bigrams := termStats(current.bigrams, previous.bigrams)
+
+	// emerging terms are those most over represented compared to the previous window
+	emerging := []models.TermStats{}
+	for _, t := range slices.Concat(terms, bigrams) {
+		if t.Count >= minEmergingCount && t.Growth > 1 {
+			emerging = append(emerging, t)
+		}
+	}
+	slices.SortFunc(emerging, func(a, b models.TermStats) int {
+		return cmp.Or(cmpDesc(a.Growth, b.Growth), b.Count-a.Count, strings.Compare(a.Term, b.Term))
+	})
+
+	return models.Terms{
+		Subreddit:     cmp.Or(query.Subreddit, p.config.key()),
+		From:          now.Add(-window),
+		To:            now,
+		Posts:         current.posts,
+		PreviousPosts: previous.posts,
+		Terms:         truncate(terms, query.Limit),
+		Bigrams:       truncate(bigrams, query.Limit),
+		Emerging:      truncate(emerging, query.Limit),
+	}
+}
+
+// recordTerms tokenizes the link's title and scores its sentiment.
+func (p *processor) recordTerms(link models.Link, now time.Time) {
+	// titles can't be edited so each post only needs tokenizing once
+	p.termsMu.Lock()
+	defer p.termsMu.Unlock()
+	if _, ok := p.titles[link.Data.Name]; ok {
+		return
+	}
+
+	created := link.Data.Created()
+	if link.Data.CreatedUTC == 0 {
+		created = now
+	}
+	terms, bigrams := tokenize(link.Data.Title, p.stopwords)
+	p.titles[link.Data.Name] = titleTerms{
+		subreddit: link.Data.Subreddit,
+		created:   created,
+		terms:     terms,
+		bigrams:   bigrams,
+		sentiment: sentiment(link.Data.Title),
+	}
+}
+
+// tokenize splits the title into its distinct lower case terms, leaving out stopwords, numbers
+// and single characters, along with the distinct bigrams of terms that are next to each other.
+func tokenize(title string, stopwords map[string]bool) (terms []string, bigrams []string) {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+	})
+
+	terms, bigrams = []string{}, []string{}
+	previous := ""
+	for _, word := range words {
+		word = strings.TrimSuffix(strings.TrimSuffix(word, "'s"), "’s")
+		word = strings.NewReplacer("'", "", "’", "").Replace(word)
+		if len([]rune(word)) < 2 || stopwords[word] || isNumber(word) {
+			previous = ""
+			continue
+		}
+		if !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+		if previous != "" {
+			bigram := previous + " " + word
+			if !slices.Contains(bigrams, bigram) {
+				bigrams = append(bigrams, bigram)
+			}
+		}
+		previous = word
+	}
+	return
+}
+
+func isNumber(word string) bool {
+	return strings.IndexFunc(word, func(r rune) bool { return !unicode.IsDigit(r) }) == -1
+}
+
+func newStopwords(extra []string) map[string]bool {
+	result := map[string]bool{}
+	for _, w := range slices.Concat(stopwords, extra) {
+		result[strings.ToLower(w)] = true
+	}
+	return result
+}
+
+func newTermCounts() termCounts {
+	return termCounts{
+		terms:   map[string]int{},
+		bigrams: map[string]int{},
+	}
+}
+
+func (c *termCounts) add(t titleTerms) {
+	c.posts++
+	for _, term := range t.terms {
+		c.terms[term]++
+	}
+	for _, bigram := range t.bigrams {
+		c.bigrams[bigram]++
+	}
+}
+
+// termStats compares the counts of each term in the current window to the previous one, sorted
+// by the current count. Growth is smoothed so that new terms don't grow infinitely.
+func termStats(current map[string]int, previous map[string]int) []models.TermStats {
+	result := []models.TermStats{}
+	for term, count := range current {
+		result = append(result, models.TermStats{
+			Term:          term,
+			Count:         count,
+			PreviousCount: previous[term],
+			Growth:        float64(count+1) / float64(previous[term]+1),
+		})
+	}
+	slices.SortFunc(result, func(a, b models.TermStats) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Term, b.Term))
+	})
+	return result
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jgkawell/reddit-api-demo/models"
+)
+
+const (
+	// trending velocity is measured over the most recent window of score history
+	trendingWindow = 30 * time.Minute
+	// the trending score of a post halves every half life since it was created
+	trendingHalfLife = 6 * time.Hour
+)
+
+// Trending returns the posts that are currently gaining upvotes the fastest. If a subreddit is
+// given only its posts are included.
+func (p *processor) Trending(_ context.Context, subreddit string, limit int) []models.LinkStats {
+	links := visible(p.linkStats(time.Now(), linkFilter{}.inSubreddit(subreddit)))
+	sortLinks(links, models.SortTrending)
+	return truncate(links, limit)
+}
+
+// trendingScore is the number of upvotes per minute a post is gaining with a decay applied
+// based on its age. The velocity is taken from the score history over the trending window when
+// there is enough of it, otherwise from the average since the post was created.
+func trendingScore(link models.LinkData, points []models.ScorePoint, now time.Time) float64 {
+	created := link.Created()
+	if link.CreatedUTC == 0 && len(points) > 0 {
+		created = points[0].Time
+	}
+	age := max(now.Sub(created), time.Minute)
+
+	velocity := float64(link.Ups) / age.Minutes()
+	if n := len(points); n > 1 {
+		latest := points[n-1]
+		i, _ := slices.BinarySearchFunc(points, latest.Time.Add(-trendingWindow), func(p models.ScorePoint, t time.Time) int {
+			return p.Time.Compare(t)
+		})
+		earliest := points[min(i, n-2)]
+		if elapsed := latest.Time.Sub(earliest.Time); elapsed >= time.Minute {
+			velocity = float64(latest.Ups-earliest.Ups) / elapsed.Minutes()
+		}
+	}
+
+	decay := math.Pow(0.5, age.Hours()/trendingHalfLife.Hours())
+	return max(velocity, 0) * decay
+}
+
+func sortLinks(links []models.LinkStats, sort models.StatsSort) {
+	switch sort {
+	case models.SortTrending:
+		slices.SortFunc(links, func(a, b models.LinkStats) int {
+			return cmpDesc(a.Trending, b.Trending)
+		})
+	default:
+		slices.SortFunc(links, func(a, b models.LinkStats) int {
+			return b.UpVotes - a.UpVotes
+		})
+	}
+}
+
+func cmpDesc(a, b float64) int {
+	switch {
+	case a > b:
+		return -1
+	case a < b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -2,20 +2,31 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jgkawell/reddit-api-demo/controller"
 	"github.com/jgkawell/reddit-api-demo/models"
+	"github.com/jgkawell/reddit-api-demo/tracing"
 
 	"github.com/steady-bytes/draft/pkg/chassis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
 	// Handler implements the chassis RPCRegistrar interface so its lifecycle can be
 	// managed automatically by the chassis. On the network it will expose the /api/stats
-	// path for returning stats to the caller.
+	// path for returning stats to the caller along with the /healthz and /readyz probes.
 	Handler interface {
 		chassis.RPCRegistrar
 	}
@@ -26,6 +37,10 @@ type (
 	}
 )
 
+const defaultLimit = 5
+
+var tracer = otel.Tracer("github.com/jgkawell/reddit-api-demo/handler")
+
 func NewHandler(logger chassis.Logger, ctrl controller.Controller) Handler {
 	return &handler{
 		logger,
@@ -34,40 +49,526 @@ func NewHandler(logger chassis.Logger, ctrl controller.Controller) Handler {
 }
 
 func (h *handler) RegisterRPC(server chassis.Rpcer) {
-	server.AddHandler("/api/stats", http.HandlerFunc(h.statsHandler), false)
+	server.AddHandler("/api/stats", traced("/api/stats", h.statsHandler), false)
+	server.AddHandler("/api/trending", traced("/api/trending", h.trendingHandler), false)
+	server.AddHandler("/api/terms", traced("/api/terms", h.termsHandler), false)
+	server.AddHandler("/api/heatmap", traced("/api/heatmap", h.heatmapHandler), false)
+	server.AddHandler("/api/reposts", traced("/api/reposts", h.repostsHandler), false)
+	server.AddHandler("/api/removed", traced("/api/removed", h.removedHandler), false)
+	server.AddHandler("/api/rankings", traced("/api/rankings", h.rankingsHandler), false)
+	server.AddHandler("/api/posts/", traced("/api/posts/{fullname}/history", h.historyHandler), false)
+	server.AddHandler("/api/users/", traced("/api/users/{name}", h.userHandler), false)
+	server.AddHandler("/api/predictions/backtest", traced("/api/predictions/backtest", h.backtestHandler), false)
+	server.AddHandler("/api/backfill", traced("/api/backfill", h.backfillHandler), false)
+	server.AddHandler("/api/overlap", traced("/api/overlap", h.overlapHandler), false)
+	server.AddHandler("/api/overlap/authors", traced("/api/overlap/authors", h.crossActiveHandler), false)
+	server.AddHandler("/api/anomalies", traced("/api/anomalies", h.anomaliesHandler), false)
+	server.AddHandler("/api/alerts", traced("/api/alerts", h.alertsHandler), false)
+	server.AddHandler("/healthz", http.HandlerFunc(h.healthHandler), false)
+	server.AddHandler("/readyz", http.HandlerFunc(h.readyHandler), false)
 }
 
 // params:
-//   - sub <string>: the subreddit to return the stats for
+//   - sub <string>: the subreddit to return the stats for, "u/{name}" for a followed user or
+//     "search/{name}" for a search
 //   - limit <int>: the limit of posts and users to return (optional)
+//   - sort <string>: how to sort posts, either "top" or "trending" (optional)
+//   - subreddit <string>: only include posts, users and comments from this subreddit (optional)
+//   - group <string>: set to "subreddit" to group the top posts and users by subreddit (optional)
+//   - title, exclude_title <string>: regular expressions post titles must or must not match (optional)
+//   - flair, exclude_flair, author_flair, exclude_author_flair, domain, exclude_domain, author,
+//     exclude_author <string>: values to allow or deny, may be repeated (optional)
+//   - type <string>: "self", "image", "video", "gallery" or "link", may be repeated (optional)
+//   - nsfw, stickied <bool>: only include posts that are (or aren't) NSFW or stickied (optional)
+//   - min_upvotes <int>: only include posts with at least this many upvotes (optional)
+//   - max_suspicion <float>: leave out authors with a higher spam suspicion score, from 0 to 1 (optional)
+//   - include_removed <bool>: include posts that were removed or deleted (optional)
+//   - window <duration>: only count posts created within the window in the domain and media
+//     type leaderboards, e.g. "24h" (optional)
 // returns:
 //   - models.Stats{}
 func (h *handler) statsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+	span.SetAttributes(tracing.SubredditKey.String(params.Get("sub")))
+
+	query := models.StatsQuery{
+		Limit:     h.limit(params),
+		Sort:      models.SortTop,
+		Subreddit: params.Get("subreddit"),
+	}
+	switch sort := models.StatsSort(params.Get("sort")); sort {
+	case "", models.SortTop:
+	case models.SortTrending:
+		query.Sort = sort
+	default:
+		h.error(w, r, fmt.Errorf("unknown sort %q", sort), http.StatusBadRequest, "failed to parse sort param")
+		return
+	}
+	switch group := models.StatsGroup(params.Get("group")); group {
+	case "":
+	case models.GroupSubreddit:
+		query.Group = group
+	default:
+		h.error(w, r, fmt.Errorf("unknown group %q", group), http.StatusBadRequest, "failed to parse group param")
+		return
+	}
+	query.Filter, err = filter(params)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse filter params")
+		return
+	}
+	if params.Has("window") {
+		query.Window, err = time.ParseDuration(params.Get("window"))
+		if err != nil {
+			h.error(w, r, err, http.StatusBadRequest, "failed to parse window param")
+			return
+		}
+	}
+	if params.Has("include_removed") {
+		query.IncludeRemoved, err = strconv.ParseBool(params.Get("include_removed"))
+		if err != nil {
+			h.error(w, r, err, http.StatusBadRequest, "failed to parse include_removed param")
+			return
+		}
+	}
+	if params.Has("max_suspicion") {
+		maxSuspicion, err := strconv.ParseFloat(params.Get("max_suspicion"), 64)
+		if err != nil {
+			h.error(w, r, err, http.StatusBadRequest, "failed to parse max_suspicion param")
+			return
+		}
+		if maxSuspicion < 0 || maxSuspicion > 1 {
+			h.error(w, r, fmt.Errorf("max_suspicion must be between 0 and 1, got %v", maxSuspicion), http.StatusBadRequest, "failed to parse max_suspicion param")
+			return
+		}
+		query.MaxSuspicion = &maxSuspicion
+	}
+
+	stats, err := h.controller.Stats(ctx, params.Get("sub"), query)
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect stats")
+		return
+	}
+	span.SetAttributes(tracing.ResultCountKey.Int(len(stats.Posts)))
+
+	writeJSON(w, stats)
+}
+
+// params:
+//   - sub <string>: the subreddit to return the trending posts for
+//   - limit <int>: the limit of posts to return (optional)
+// returns:
+//   - []models.LinkStats{}
+func (h *handler) trendingHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	links, err := h.controller.Trending(r.Context(), params.Get("sub"), h.limit(params))
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect trending posts")
+		return
+	}
+
+	writeJSON(w, links)
+}
+
+// params:
+//   - sub <string>: the subreddit to return the rankings for
+//   - limit <int>: the limit of posts to return per listing (optional)
+// returns:
+//   - models.Rankings{}
+func (h *handler) rankingsHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	rankings, err := h.controller.Rankings(r.Context(), params.Get("sub"), h.limit(params))
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect rankings")
+		return
+	}
+
+	writeJSON(w, rankings)
+}
+
+// params:
+//   - sub <string>: the subreddit to return the title terms for
+//   - window <duration>: the window to count terms over, e.g. "24h" (optional)
+//   - limit <int>: the limit of terms, bigrams and emerging terms to return (optional)
+// returns:
+//   - models.Terms{}
+func (h *handler) termsHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	query := models.TermsQuery{
+		Limit: h.limit(params),
+	}
+	if params.Has("window") {
+		query.Window, err = time.ParseDuration(params.Get("window"))
+		if err != nil {
+			h.error(w, r, err, http.StatusBadRequest, "failed to parse window param")
+			return
+		}
+	}
+
+	terms, err := h.controller.Terms(r.Context(), params.Get("sub"), query)
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect terms")
+		return
+	}
+
+	writeJSON(w, terms)
+}
+
+// params:
+//   - sub <string>: the subreddit to return the posting time heatmap for
+//   - tz <string>: the IANA timezone to bucket posting times in, e.g. "America/New_York"
+//     (optional, defaults to the configured timezone or UTC)
+//   - limit <int>: the limit of best times to post to return (optional)
+// returns:
+//   - models.Heatmap{}
+func (h *handler) heatmapHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	query := models.HeatmapQuery{
+		Timezone: params.Get("tz"),
+		Limit:    h.limit(params),
+	}
+	if _, err = time.LoadLocation(query.Timezone); err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse tz param")
+		return
+	}
+
+	heatmap, err := h.controller.Heatmap(r.Context(), params.Get("sub"), query)
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect heatmap")
+		return
+	}
+
+	writeJSON(w, heatmap)
+}
+
+// params:
+//   - sub <string>: the subreddit to return the likely reposts for
+//   - limit <int>: the limit of reposts to return (optional)
+// returns:
+//   - []models.Repost{} with the most recent first
+func (h *handler) repostsHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	reposts, err := h.controller.Reposts(r.Context(), params.Get("sub"), h.limit(params))
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect reposts")
+		return
+	}
+
+	writeJSON(w, reposts)
+}
+
+// params:
+//   - sub <string>: the subreddit to return the removed and deleted posts for
+//   - limit <int>: the limit of posts to return (optional)
+// returns:
+//   - []models.Removal{} with the most recently detected first
+func (h *handler) removedHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	removals, err := h.controller.Removed(r.Context(), params.Get("sub"), h.limit(params))
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect removed posts")
+		return
+	}
 
+	writeJSON(w, removals)
+}
+
+// path:
+//   - /api/posts/{fullname}/history: the fullname of the post to return the history for
+// returns:
+//   - models.PostHistory{}
+func (h *handler) historyHandler(w http.ResponseWriter, r *http.Request) {
+	fullname, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/posts/"), "/")
+	if fullname == "" || rest != "history" {
+		http.NotFound(w, r)
+		return
+	}
+
+	history, err := h.controller.History(r.Context(), fullname)
+	if errors.Is(err, controller.ErrNotFound) {
+		h.error(w, r, err, http.StatusNotFound, "post not tracked")
+		return
+	}
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect post history")
+		return
+	}
+
+	writeJSON(w, history)
+}
+
+// path:
+//   - /api/users/{name}: the name of the user to return the profile for
+// returns:
+//   - models.UserProfile{}
+func (h *handler) userHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/users/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	profile, err := h.controller.User(r.Context(), name)
+	if errors.Is(err, controller.ErrNotFound) {
+		h.error(w, r, err, http.StatusNotFound, "user not tracked")
+		return
+	}
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect user profile")
+		return
+	}
+
+	writeJSON(w, profile)
+}
+
+// params:
+//   - sub <string>: the subreddit to backtest score predictions for
+// returns:
+//   - models.Backtest{}
+func (h *handler) backtestHandler(w http.ResponseWriter, r *http.Request) {
+	backtest, err := h.controller.Backtest(r.Context(), r.URL.Query().Get("sub"))
+	if errors.Is(err, controller.ErrNotEnoughHistory) {
+		h.error(w, r, err, http.StatusConflict, "not enough history to backtest predictions")
+		return
+	}
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to backtest predictions")
+		return
+	}
+
+	writeJSON(w, backtest)
+}
+
+// params:
+//   - sub <string>: the subreddit to return the backfill progress for
+// returns:
+//   - []models.BackfillProgress{}
+func (h *handler) backfillHandler(w http.ResponseWriter, r *http.Request) {
+	progress, err := h.controller.Backfill(r.Context(), r.URL.Query().Get("sub"))
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect backfill progress")
+		return
+	}
+
+	writeJSON(w, progress)
+}
+
+// params:
+//   - sub <string>: only return the pairs that include this subreddit (optional)
+//   - limit <int>: the limit of subreddit pairs to return (optional)
+// returns:
+//   - []models.SubredditOverlap{} with the most similar pairs first
+func (h *handler) overlapHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	writeJSON(w, h.controller.Overlap(r.Context(), params.Get("sub"), h.limit(params)))
+}
+
+// params:
+//   - limit <int>: the limit of authors to return (optional)
+// returns:
+//   - []models.CrossActiveAuthor{} with the authors active in the most subreddits first
+func (h *handler) crossActiveHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	writeJSON(w, h.controller.CrossActive(r.Context(), h.limit(params)))
+}
+
+// params:
+//   - sub <string>: the subreddit to return the volume anomaly state for (optional, defaults to
+//     every target with anomaly detection enabled)
+// returns:
+//   - []models.Anomaly{}
+func (h *handler) anomaliesHandler(w http.ResponseWriter, r *http.Request) {
 	params, err := url.ParseQuery(r.URL.RawQuery)
 	if err != nil {
-		h.logger.WithError(err).Error("failed to parse query params")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	anomalies, err := h.controller.Anomalies(r.Context(), params.Get("sub"))
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError, "failed to collect anomalies")
 		return
 	}
 
+	writeJSON(w, anomalies)
+}
+
+// params:
+//   - limit <int>: the limit of alerts to return (optional)
+// returns:
+//   - []models.Alert{} with the most recent first
+func (h *handler) alertsHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest, "failed to parse query params")
+		return
+	}
+
+	writeJSON(w, h.controller.Alerts(r.Context(), h.limit(params)))
+}
+
+// returns:
+//   - models.Health{} with a 503 status if any processor is unhealthy
+func (h *handler) healthHandler(w http.ResponseWriter, r *http.Request) {
+	health := h.controller.Health(r.Context())
+	h.writeHealth(w, health, health.Healthy)
+}
+
+// returns:
+//   - models.Health{} with a 503 status until every processor is running with a valid token
+func (h *handler) readyHandler(w http.ResponseWriter, r *http.Request) {
+	health := h.controller.Health(r.Context())
+	h.writeHealth(w, health, health.Ready)
+}
+
+func (h *handler) writeHealth(w http.ResponseWriter, health models.Health, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
+}
+
+// limit reads the optional limit param, falling back to the default if it is missing or invalid.
+func (h *handler) limit(params url.Values) int {
 	limit, err := strconv.Atoi(params.Get("limit"))
 	if err != nil {
 		h.logger.WithError(err).Warn("failed to parse limit param")
-		limit = 5
+		return defaultLimit
 	}
+	return limit
+}
 
-	links, users, err := h.controller.Stats(ctx, params.Get("sub"), limit)
-	if err != nil {
-		h.logger.WithError(err).Error("failed to collect stats")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// filter reads the optional filter params.
+func filter(params url.Values) (filter models.LinkFilter, err error) {
+	filter = models.LinkFilter{
+		Title:               params.Get("title"),
+		ExcludeTitle:        params.Get("exclude_title"),
+		Flairs:              params["flair"],
+		ExcludeFlairs:       params["exclude_flair"],
+		AuthorFlairs:        params["author_flair"],
+		ExcludeAuthorFlairs: params["exclude_author_flair"],
+		Domains:             params["domain"],
+		ExcludeDomains:      params["exclude_domain"],
+		Authors:             params["author"],
+		ExcludeAuthors:      params["exclude_author"],
+	}
+	for _, expr := range []string{filter.Title, filter.ExcludeTitle} {
+		if _, err = regexp.Compile(expr); err != nil {
+			return
+		}
+	}
+	for _, t := range params["type"] {
+		switch postType := models.PostType(t); postType {
+		case models.PostSelf, models.PostImage, models.PostVideo, models.PostGallery, models.PostLink:
+			filter.Types = append(filter.Types, postType)
+		default:
+			return filter, fmt.Errorf("unknown type %q", t)
+		}
+	}
+	if filter.NSFW, err = optionalBool(params, "nsfw"); err != nil {
 		return
 	}
+	if filter.Stickied, err = optionalBool(params, "stickied"); err != nil {
+		return
+	}
+	if params.Has("min_upvotes") {
+		filter.MinUpVotes, err = strconv.Atoi(params.Get("min_upvotes"))
+		if err != nil {
+			return filter, fmt.Errorf("invalid min_upvotes param: %w", err)
+		}
+	}
+	return
+}
 
+func optionalBool(params url.Values, key string) (*bool, error) {
+	if !params.Has(key) {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(params.Get(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s param: %w", key, err)
+	}
+	return &value, nil
+}
+
+// error logs the error, records it on the request's span and returns it to the caller.
+func (h *handler) error(w http.ResponseWriter, r *http.Request, err error, status int, msg string) {
+	if status >= http.StatusInternalServerError {
+		h.logger.WithError(err).Error(msg)
+	} else {
+		h.logger.WithError(err).Warn(msg)
+	}
+	span := trace.SpanFromContext(r.Context())
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	http.Error(w, err.Error(), status)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(models.Stats{
-		Posts: links,
-		Users: users,
+	json.NewEncoder(w).Encode(v)
+}
+
+// traced wraps the handler in a server span that continues any trace propagated by the caller.
+func traced(route string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+			),
+		)
+		defer span.End()
+		next(w, r.WithContext(ctx))
 	})
 }
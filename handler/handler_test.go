@@ -10,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jgkawell/reddit-api-demo/controller"
 	"github.com/jgkawell/reddit-api-demo/mocks"
 	"github.com/jgkawell/reddit-api-demo/models"
 
@@ -63,7 +64,6 @@ var (
 )
 
 func Test_HandlerStatsHandler(t *testing.T) {
-	ctx := context.Background()
 	logger := zerolog.New()
 	ctrl := mocks.NewController(t)
 	handler := &handler{
@@ -98,6 +98,53 @@ func Test_HandlerStatsHandler(t *testing.T) {
 			expectedStats:  models.Stats{},
 			expectedErr:    errors.New("invalid semicolon separator in query"),
 		},
+		{
+			name:           "group by subreddit",
+			rawQuery:       "?sub=golang+rust&group=subreddit",
+			expectedStatus: http.StatusOK,
+			expectedStats:  stats1,
+		},
+		{
+			name:           "bad group",
+			rawQuery:       "?sub=example&group=author",
+			expectedStatus: http.StatusBadRequest,
+			expectedStats:  models.Stats{},
+			expectedErr:    errors.New(`unknown group "author"`),
+		},
+		{
+			name:           "filters",
+			rawQuery:       "?sub=example&type=image&type=video&nsfw=false&stickied=false&min_upvotes=10",
+			expectedStatus: http.StatusOK,
+			expectedStats:  stats1,
+		},
+		{
+			name:           "bad type filter",
+			rawQuery:       "?sub=example&type=poll",
+			expectedStatus: http.StatusBadRequest,
+			expectedStats:  models.Stats{},
+			expectedErr:    errors.New(`unknown type "poll"`),
+		},
+		{
+			name:           "bad nsfw filter",
+			rawQuery:       "?sub=example&nsfw=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedStats:  models.Stats{},
+			expectedErr:    errors.New(`invalid nsfw param: strconv.ParseBool: parsing "maybe": invalid syntax`),
+		},
+		{
+			name:           "bad window",
+			rawQuery:       "?sub=example&window=soon",
+			expectedStatus: http.StatusBadRequest,
+			expectedStats:  models.Stats{},
+			expectedErr:    errors.New(`time: invalid duration "soon"`),
+		},
+		{
+			name:           "bad max suspicion",
+			rawQuery:       "?sub=example&max_suspicion=1.5",
+			expectedStatus: http.StatusBadRequest,
+			expectedStats:  models.Stats{},
+			expectedErr:    errors.New("max_suspicion must be between 0 and 1, got 1.5"),
+		},
 		{
 			name:           "error",
 			rawQuery:       "?sub=example&limit=10",
@@ -110,7 +157,7 @@ func Test_HandlerStatsHandler(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			if tc.expectedStatus != http.StatusBadRequest {
-				ctrl.On("Stats", ctx, mock.Anything, mock.Anything).Once().Return(tc.expectedStats.Posts, tc.expectedStats.Users, tc.expectedErr)
+				ctrl.On("Stats", mock.Anything, mock.Anything, mock.Anything).Once().Return(tc.expectedStats, tc.expectedErr)
 			}
 
 			req, err := http.NewRequest("GET", "/api/stats", nil)
@@ -148,3 +195,145 @@ func Test_HandlerStatsHandler(t *testing.T) {
 		})
 	}
 }
+
+func Test_HandlerHealth(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New()
+	ctrl := mocks.NewController(t)
+	handler := &handler{
+		logger,
+		ctrl,
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		health         models.Health
+		expectedStatus int
+	}{
+		{
+			name:           "healthy",
+			path:           "/healthz",
+			health:         models.Health{Healthy: true},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unhealthy",
+			path:           "/healthz",
+			health:         models.Health{Healthy: false, Ready: true},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "ready",
+			path:           "/readyz",
+			health:         models.Health{Healthy: true, Ready: true},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not ready",
+			path:           "/readyz",
+			health:         models.Health{Healthy: true, Ready: false},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl.On("Health", ctx).Once().Return(tc.health)
+
+			req, err := http.NewRequest("GET", tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			h := http.HandlerFunc(handler.healthHandler)
+			if tc.path == "/readyz" {
+				h = http.HandlerFunc(handler.readyHandler)
+			}
+			h.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+
+			health := models.Health{}
+			err = json.NewDecoder(rr.Body).Decode(&health)
+			if err != nil {
+				t.Error("failed to unmarshal body")
+			}
+			assert.Equal(t, tc.health, health)
+		})
+	}
+}
+
+func Test_HandlerHistory(t *testing.T) {
+	logger := zerolog.New()
+	ctrl := mocks.NewController(t)
+	handler := &handler{
+		logger,
+		ctrl,
+	}
+
+	history := models.PostHistory{
+		Name: "t3_abc",
+		Points: []models.ScorePoint{
+			{Ups: 1},
+			{Ups: 2},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		fullname       string
+		history        models.PostHistory
+		err            error
+		expectedStatus int
+	}{
+		{
+			name:           "happy",
+			path:           "/api/posts/t3_abc/history",
+			fullname:       "t3_abc",
+			history:        history,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not tracked",
+			path:           "/api/posts/t3_xyz/history",
+			fullname:       "t3_xyz",
+			err:            controller.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "bad path",
+			path:           "/api/posts/t3_abc",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.fullname != "" {
+				ctrl.On("History", mock.Anything, tc.fullname).Once().Return(tc.history, tc.err)
+			}
+
+			req, err := http.NewRequest("GET", tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			h := http.HandlerFunc(handler.historyHandler)
+			h.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				result := models.PostHistory{}
+				err = json.NewDecoder(rr.Body).Decode(&result)
+				if err != nil {
+					t.Error("failed to unmarshal body")
+				}
+				assert.Equal(t, tc.history, result)
+			}
+		})
+	}
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"os"
+
 	"github.com/jgkawell/reddit-api-demo/controller"
 	"github.com/jgkawell/reddit-api-demo/handler"
+	"github.com/jgkawell/reddit-api-demo/tracing"
 
 	"github.com/steady-bytes/draft/pkg/chassis"
 	"github.com/steady-bytes/draft/pkg/loggers/zerolog"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		backtest(os.Args[2:])
+		return
+	}
 
 	var (
 		logger = zerolog.New()
@@ -16,7 +23,12 @@ func main() {
 		hnd    = handler.NewHandler(logger, ctrl)
 	)
 
-	chassis.New(logger).
+	// the chassis loads the config so tracing can only be set up after it is created
+	runtime := chassis.New(logger)
+	shutdown := tracing.Init(logger)
+	defer shutdown()
+
+	runtime.
 		WithRPCHandler(hnd).
 		WithRunner(ctrl.Start).
 		Start()
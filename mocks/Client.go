@@ -48,6 +48,34 @@ func (_m *Client) Get(ctx context.Context, _a1 string, values url.Values) (*http
 	return r0, r1
 }
 
+// GetCommentListing provides a mock function with given fields: ctx, _a1, values
+func (_m *Client) GetCommentListing(ctx context.Context, _a1 string, values url.Values) (models.CommentListing, error) {
+	ret := _m.Called(ctx, _a1, values)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCommentListing")
+	}
+
+	var r0 models.CommentListing
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, url.Values) (models.CommentListing, error)); ok {
+		return rf(ctx, _a1, values)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, url.Values) models.CommentListing); ok {
+		r0 = rf(ctx, _a1, values)
+	} else {
+		r0 = ret.Get(0).(models.CommentListing)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, url.Values) error); ok {
+		r1 = rf(ctx, _a1, values)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetLinkListing provides a mock function with given fields: ctx, _a1, values
 func (_m *Client) GetLinkListing(ctx context.Context, _a1 string, values url.Values) (models.Listing, error) {
 	ret := _m.Called(ctx, _a1, values)
@@ -76,6 +104,80 @@ func (_m *Client) GetLinkListing(ctx context.Context, _a1 string, values url.Val
 	return r0, r1
 }
 
+// GetSubredditAbout provides a mock function with given fields: ctx, _a1, values
+func (_m *Client) GetSubredditAbout(ctx context.Context, _a1 string, values url.Values) (models.SubredditAbout, error) {
+	ret := _m.Called(ctx, _a1, values)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSubredditAbout")
+	}
+
+	var r0 models.SubredditAbout
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, url.Values) (models.SubredditAbout, error)); ok {
+		return rf(ctx, _a1, values)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, url.Values) models.SubredditAbout); ok {
+		r0 = rf(ctx, _a1, values)
+	} else {
+		r0 = ret.Get(0).(models.SubredditAbout)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, url.Values) error); ok {
+		r1 = rf(ctx, _a1, values)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserAbout provides a mock function with given fields: ctx, _a1, values
+func (_m *Client) GetUserAbout(ctx context.Context, _a1 string, values url.Values) (models.UserAbout, error) {
+	ret := _m.Called(ctx, _a1, values)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserAbout")
+	}
+
+	var r0 models.UserAbout
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, url.Values) (models.UserAbout, error)); ok {
+		return rf(ctx, _a1, values)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, url.Values) models.UserAbout); ok {
+		r0 = rf(ctx, _a1, values)
+	} else {
+		r0 = ret.Get(0).(models.UserAbout)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, url.Values) error); ok {
+		r1 = rf(ctx, _a1, values)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TokenValid provides a mock function with no fields
+func (_m *Client) TokenValid() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TokenValid")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // NewClient creates a new instance of Client. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewClient(t interface {
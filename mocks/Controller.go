@@ -15,23 +15,388 @@ type Controller struct {
 	mock.Mock
 }
 
+// Alerts provides a mock function with given fields: ctx, limit
+func (_m *Controller) Alerts(ctx context.Context, limit int) []models.Alert {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Alerts")
+	}
+
+	var r0 []models.Alert
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.Alert); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Alert)
+		}
+	}
+
+	return r0
+}
+
+// Anomalies provides a mock function with given fields: ctx, subreddit
+func (_m *Controller) Anomalies(ctx context.Context, subreddit string) ([]models.Anomaly, error) {
+	ret := _m.Called(ctx, subreddit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Anomalies")
+	}
+
+	var r0 []models.Anomaly
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.Anomaly, error)); ok {
+		return rf(ctx, subreddit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.Anomaly); ok {
+		r0 = rf(ctx, subreddit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Anomaly)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, subreddit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Backfill provides a mock function with given fields: ctx, subreddit
+func (_m *Controller) Backfill(ctx context.Context, subreddit string) ([]models.BackfillProgress, error) {
+	ret := _m.Called(ctx, subreddit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Backfill")
+	}
+
+	var r0 []models.BackfillProgress
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.BackfillProgress, error)); ok {
+		return rf(ctx, subreddit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.BackfillProgress); ok {
+		r0 = rf(ctx, subreddit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.BackfillProgress)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, subreddit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Backtest provides a mock function with given fields: ctx, subreddit
+func (_m *Controller) Backtest(ctx context.Context, subreddit string) (models.Backtest, error) {
+	ret := _m.Called(ctx, subreddit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Backtest")
+	}
+
+	var r0 models.Backtest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Backtest, error)); ok {
+		return rf(ctx, subreddit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Backtest); ok {
+		r0 = rf(ctx, subreddit)
+	} else {
+		r0 = ret.Get(0).(models.Backtest)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, subreddit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CrossActive provides a mock function with given fields: ctx, limit
+func (_m *Controller) CrossActive(ctx context.Context, limit int) []models.CrossActiveAuthor {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CrossActive")
+	}
+
+	var r0 []models.CrossActiveAuthor
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.CrossActiveAuthor); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.CrossActiveAuthor)
+		}
+	}
+
+	return r0
+}
+
+// Health provides a mock function with given fields: ctx
+func (_m *Controller) Health(ctx context.Context) models.Health {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Health")
+	}
+
+	var r0 models.Health
+	if rf, ok := ret.Get(0).(func(context.Context) models.Health); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.Health)
+	}
+
+	return r0
+}
+
+// Heatmap provides a mock function with given fields: ctx, subreddit, query
+func (_m *Controller) Heatmap(ctx context.Context, subreddit string, query models.HeatmapQuery) (models.Heatmap, error) {
+	ret := _m.Called(ctx, subreddit, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Heatmap")
+	}
+
+	var r0 models.Heatmap
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.HeatmapQuery) (models.Heatmap, error)); ok {
+		return rf(ctx, subreddit, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.HeatmapQuery) models.Heatmap); ok {
+		r0 = rf(ctx, subreddit, query)
+	} else {
+		r0 = ret.Get(0).(models.Heatmap)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.HeatmapQuery) error); ok {
+		r1 = rf(ctx, subreddit, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// History provides a mock function with given fields: ctx, fullname
+func (_m *Controller) History(ctx context.Context, fullname string) (models.PostHistory, error) {
+	ret := _m.Called(ctx, fullname)
+
+	if len(ret) == 0 {
+		panic("no return value specified for History")
+	}
+
+	var r0 models.PostHistory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.PostHistory, error)); ok {
+		return rf(ctx, fullname)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.PostHistory); ok {
+		r0 = rf(ctx, fullname)
+	} else {
+		r0 = ret.Get(0).(models.PostHistory)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fullname)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Overlap provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Controller) Overlap(ctx context.Context, subreddit string, limit int) []models.SubredditOverlap {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Overlap")
+	}
+
+	var r0 []models.SubredditOverlap
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.SubredditOverlap); ok {
+		r0 = rf(ctx, subreddit, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.SubredditOverlap)
+		}
+	}
+
+	return r0
+}
+
+// Rankings provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Controller) Rankings(ctx context.Context, subreddit string, limit int) (models.Rankings, error) {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rankings")
+	}
+
+	var r0 models.Rankings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (models.Rankings, error)); ok {
+		return rf(ctx, subreddit, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) models.Rankings); ok {
+		r0 = rf(ctx, subreddit, limit)
+	} else {
+		r0 = ret.Get(0).(models.Rankings)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, subreddit, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Removed provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Controller) Removed(ctx context.Context, subreddit string, limit int) ([]models.Removal, error) {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Removed")
+	}
+
+	var r0 []models.Removal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]models.Removal, error)); ok {
+		return rf(ctx, subreddit, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.Removal); ok {
+		r0 = rf(ctx, subreddit, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Removal)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, subreddit, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reposts provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Controller) Reposts(ctx context.Context, subreddit string, limit int) ([]models.Repost, error) {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reposts")
+	}
+
+	var r0 []models.Repost
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]models.Repost, error)); ok {
+		return rf(ctx, subreddit, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.Repost); ok {
+		r0 = rf(ctx, subreddit, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Repost)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, subreddit, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Start provides a mock function with no fields
 func (_m *Controller) Start() {
 	_m.Called()
 }
 
-// Stats provides a mock function with given fields: ctx, subreddit, limit
-func (_m *Controller) Stats(ctx context.Context, subreddit string, limit int) ([]models.LinkStats, []models.UserStats, error) {
-	ret := _m.Called(ctx, subreddit, limit)
+// Stats provides a mock function with given fields: ctx, subreddit, query
+func (_m *Controller) Stats(ctx context.Context, subreddit string, query models.StatsQuery) (models.Stats, error) {
+	ret := _m.Called(ctx, subreddit, query)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Stats")
 	}
 
+	var r0 models.Stats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StatsQuery) (models.Stats, error)); ok {
+		return rf(ctx, subreddit, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StatsQuery) models.Stats); ok {
+		r0 = rf(ctx, subreddit, query)
+	} else {
+		r0 = ret.Get(0).(models.Stats)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.StatsQuery) error); ok {
+		r1 = rf(ctx, subreddit, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Terms provides a mock function with given fields: ctx, subreddit, query
+func (_m *Controller) Terms(ctx context.Context, subreddit string, query models.TermsQuery) (models.Terms, error) {
+	ret := _m.Called(ctx, subreddit, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Terms")
+	}
+
+	var r0 models.Terms
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.TermsQuery) (models.Terms, error)); ok {
+		return rf(ctx, subreddit, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.TermsQuery) models.Terms); ok {
+		r0 = rf(ctx, subreddit, query)
+	} else {
+		r0 = ret.Get(0).(models.Terms)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.TermsQuery) error); ok {
+		r1 = rf(ctx, subreddit, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Trending provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Controller) Trending(ctx context.Context, subreddit string, limit int) ([]models.LinkStats, error) {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Trending")
+	}
+
 	var r0 []models.LinkStats
-	var r1 []models.UserStats
-	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]models.LinkStats, []models.UserStats, error)); ok {
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]models.LinkStats, error)); ok {
 		return rf(ctx, subreddit, limit)
 	}
 	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.LinkStats); ok {
@@ -42,21 +407,41 @@ func (_m *Controller) Stats(ctx context.Context, subreddit string, limit int) ([
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, int) []models.UserStats); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
 		r1 = rf(ctx, subreddit, limit)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).([]models.UserStats)
-		}
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// User provides a mock function with given fields: ctx, name
+func (_m *Controller) User(ctx context.Context, name string) (models.UserProfile, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for User")
+	}
+
+	var r0 models.UserProfile
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.UserProfile, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.UserProfile); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Get(0).(models.UserProfile)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, string, int) error); ok {
-		r2 = rf(ctx, subreddit, limit)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
 	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
 // NewController creates a new instance of Controller. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -15,48 +15,311 @@ type Processor struct {
 	mock.Mock
 }
 
+// Anomaly provides a mock function with given fields: ctx, subreddit
+func (_m *Processor) Anomaly(ctx context.Context, subreddit string) (models.Anomaly, bool) {
+	ret := _m.Called(ctx, subreddit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Anomaly")
+	}
+
+	var r0 models.Anomaly
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Anomaly, bool)); ok {
+		return rf(ctx, subreddit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Anomaly); ok {
+		r0 = rf(ctx, subreddit)
+	} else {
+		r0 = ret.Get(0).(models.Anomaly)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, subreddit)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// Backfill provides a mock function with no fields
+func (_m *Processor) Backfill() []models.BackfillProgress {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Backfill")
+	}
+
+	var r0 []models.BackfillProgress
+	if rf, ok := ret.Get(0).(func() []models.BackfillProgress); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.BackfillProgress)
+		}
+	}
+
+	return r0
+}
+
+// Backtest provides a mock function with given fields: ctx, subreddit
+func (_m *Processor) Backtest(ctx context.Context, subreddit string) (models.Backtest, error) {
+	ret := _m.Called(ctx, subreddit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Backtest")
+	}
+
+	var r0 models.Backtest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Backtest, error)); ok {
+		return rf(ctx, subreddit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Backtest); ok {
+		r0 = rf(ctx, subreddit)
+	} else {
+		r0 = ret.Get(0).(models.Backtest)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, subreddit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Heatmap provides a mock function with given fields: ctx, query
+func (_m *Processor) Heatmap(ctx context.Context, query models.HeatmapQuery) (models.Heatmap, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Heatmap")
+	}
+
+	var r0 models.Heatmap
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.HeatmapQuery) (models.Heatmap, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.HeatmapQuery) models.Heatmap); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(models.Heatmap)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.HeatmapQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// History provides a mock function with given fields: ctx, fullname
+func (_m *Processor) History(ctx context.Context, fullname string) (models.PostHistory, bool) {
+	ret := _m.Called(ctx, fullname)
+
+	if len(ret) == 0 {
+		panic("no return value specified for History")
+	}
+
+	var r0 models.PostHistory
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.PostHistory, bool)); ok {
+		return rf(ctx, fullname)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.PostHistory); ok {
+		r0 = rf(ctx, fullname)
+	} else {
+		r0 = ret.Get(0).(models.PostHistory)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, fullname)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// Rankings provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Processor) Rankings(ctx context.Context, subreddit string, limit int) models.Rankings {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rankings")
+	}
+
+	var r0 models.Rankings
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) models.Rankings); ok {
+		r0 = rf(ctx, subreddit, limit)
+	} else {
+		r0 = ret.Get(0).(models.Rankings)
+	}
+
+	return r0
+}
+
+// Removed provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Processor) Removed(ctx context.Context, subreddit string, limit int) []models.Removal {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Removed")
+	}
+
+	var r0 []models.Removal
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.Removal); ok {
+		r0 = rf(ctx, subreddit, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Removal)
+		}
+	}
+
+	return r0
+}
+
+// Reposts provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Processor) Reposts(ctx context.Context, subreddit string, limit int) []models.Repost {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reposts")
+	}
+
+	var r0 []models.Repost
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.Repost); ok {
+		r0 = rf(ctx, subreddit, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Repost)
+		}
+	}
+
+	return r0
+}
+
 // Start provides a mock function with no fields
 func (_m *Processor) Start() {
 	_m.Called()
 }
 
-// Stats provides a mock function with given fields: ctx, limit
-func (_m *Processor) Stats(ctx context.Context, limit int) ([]models.LinkStats, []models.UserStats, error) {
-	ret := _m.Called(ctx, limit)
+// Stats provides a mock function with given fields: ctx, query
+func (_m *Processor) Stats(ctx context.Context, query models.StatsQuery) (models.Stats, error) {
+	ret := _m.Called(ctx, query)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Stats")
 	}
 
-	var r0 []models.LinkStats
-	var r1 []models.UserStats
-	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.LinkStats, []models.UserStats, error)); ok {
-		return rf(ctx, limit)
+	var r0 models.Stats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.StatsQuery) (models.Stats, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.StatsQuery) models.Stats); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(models.Stats)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int) []models.LinkStats); ok {
-		r0 = rf(ctx, limit)
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.StatsQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Status provides a mock function with no fields
+func (_m *Processor) Status() models.ProcessorStatus {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Status")
+	}
+
+	var r0 models.ProcessorStatus
+	if rf, ok := ret.Get(0).(func() models.ProcessorStatus); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(models.ProcessorStatus)
+	}
+
+	return r0
+}
+
+// Terms provides a mock function with given fields: ctx, query
+func (_m *Processor) Terms(ctx context.Context, query models.TermsQuery) models.Terms {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Terms")
+	}
+
+	var r0 models.Terms
+	if rf, ok := ret.Get(0).(func(context.Context, models.TermsQuery) models.Terms); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(models.Terms)
+	}
+
+	return r0
+}
+
+// Trending provides a mock function with given fields: ctx, subreddit, limit
+func (_m *Processor) Trending(ctx context.Context, subreddit string, limit int) []models.LinkStats {
+	ret := _m.Called(ctx, subreddit, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Trending")
+	}
+
+	var r0 []models.LinkStats
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.LinkStats); ok {
+		r0 = rf(ctx, subreddit, limit)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.LinkStats)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int) []models.UserStats); ok {
-		r1 = rf(ctx, limit)
+	return r0
+}
+
+// User provides a mock function with given fields: ctx, name
+func (_m *Processor) User(ctx context.Context, name string) (models.UserStats, bool) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for User")
+	}
+
+	var r0 models.UserStats
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.UserStats, bool)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.UserStats); ok {
+		r0 = rf(ctx, name)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).([]models.UserStats)
-		}
+		r0 = ret.Get(0).(models.UserStats)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, int) error); ok {
-		r2 = rf(ctx, limit)
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, name)
 	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Get(1).(bool)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
 // NewProcessor creates a new instance of Processor. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"strings"
+	"time"
+)
+
 type (
 
 	// Reddit API models
@@ -18,28 +23,537 @@ type (
 		Data LinkData
 	}
 	LinkData struct {
+		Name            string
+		Subreddit       string
+		AuthorFullname  string `json:"author_fullname"`
+		Title           string
+		Author          string
+		Permalink       string
+		URL             string
+		Domain          string
+		LinkFlairText   string `json:"link_flair_text"`
+		AuthorFlairText string `json:"author_flair_text"`
+		Over18          bool   `json:"over_18"`
+		Stickied        bool
+		IsSelf          bool   `json:"is_self"`
+		IsVideo         bool   `json:"is_video"`
+		IsGallery       bool   `json:"is_gallery"`
+		PostHint        string `json:"post_hint"`
+		Selftext        string
+		RemovedBy       string `json:"removed_by_category"`
+		Ups             int
+		UpvoteRatio     float64 `json:"upvote_ratio"`
+		NumComments     int     `json:"num_comments"`
+		CreatedUTC      float64 `json:"created_utc"`
+	}
+	UserAbout struct {
+		Kind string
+		Data UserAboutData
+	}
+	UserAboutData struct {
+		Name         string
+		CreatedUTC   float64 `json:"created_utc"`
+		LinkKarma    int     `json:"link_karma"`
+		CommentKarma int     `json:"comment_karma"`
+		IsSuspended  bool    `json:"is_suspended"`
+		Verified     bool
+	}
+	SubredditAbout struct {
+		Kind string
+		Data SubredditAboutData
+	}
+	SubredditAboutData struct {
+		DisplayName     string  `json:"display_name"`
+		Subscribers     int     `json:"subscribers"`
+		ActiveUserCount int     `json:"active_user_count"`
+		Over18          bool    `json:"over18"`
+		Quarantine      bool    `json:"quarantine"`
+		CreatedUTC      float64 `json:"created_utc"`
+	}
+	CommentListing struct {
+		Kind string
+		Data CommentListingData
+	}
+	CommentListingData struct {
+		After    *string
+		Before   *string
+		Children []Comment
+	}
+	Comment struct {
+		Kind string
+		Data CommentData
+	}
+	CommentData struct {
 		Name           string
 		Subreddit      string
 		AuthorFullname string `json:"author_fullname"`
-		Title          string
 		Author         string
-		Ups            int
-	}
-	Stats struct {
-		Posts []LinkStats
-		Users []UserStats
+		Body           string
+		Score          int
+		LinkID         string  `json:"link_id"`
+		CreatedUTC     float64 `json:"created_utc"`
 	}
 
 	// Service API models
 
+	StatsQuery struct {
+		Limit          int
+		Sort           StatsSort
+		Subreddit      string
+		Group          StatsGroup
+		Filter         LinkFilter
+		MaxSuspicion   *float64
+		IncludeRemoved bool
+		Window         time.Duration
+	}
+	LinkFilter struct {
+		Title               string
+		ExcludeTitle        string
+		Flairs              []string
+		ExcludeFlairs       []string
+		AuthorFlairs        []string
+		ExcludeAuthorFlairs []string
+		Domains             []string
+		ExcludeDomains      []string
+		Types               []PostType
+		Authors             []string
+		ExcludeAuthors      []string
+		NSFW                *bool
+		Stickied            *bool
+		MinUpVotes          int
+	}
+	Stats struct {
+		Subreddit         *SubredditStats
+		Subreddits        []SubredditBreakdown
+		Sentiment         *SentimentStats
+		Removals          *RemovalStats
+		Media             *MediaStats
+		Flairs            *FlairStats
+		Posts             []LinkStats
+		Users             []UserStats
+		Comments          []CommentStats
+		Commenters        []CommenterStats
+		CommentersByScore []CommenterStats
+	}
+	HeatmapQuery struct {
+		Timezone  string
+		Subreddit string
+		Limit     int
+	}
+	Heatmap struct {
+		Subreddit string
+		Timezone  string
+		Posts     int
+		Cells     []HeatmapCell
+		Best      []HeatmapCell
+	}
+	HeatmapCell struct {
+		Day           string
+		Hour          int
+		PostCount     int
+		MedianUpVotes float64
+	}
+	TermsQuery struct {
+		Window    time.Duration
+		Limit     int
+		Subreddit string
+	}
+	Terms struct {
+		Subreddit     string
+		From          time.Time
+		To            time.Time
+		Posts         int
+		PreviousPosts int
+		Terms         []TermStats
+		Bigrams       []TermStats
+		Emerging      []TermStats
+	}
+	TermStats struct {
+		Term          string
+		Count         int
+		PreviousCount int
+		Growth        float64
+	}
+	SubredditBreakdown struct {
+		Subreddit      string
+		PostCount      int
+		TotalUpVotes   int
+		AverageUpVotes float64
+		Posts          []LinkStats
+		Users          []UserStats
+	}
+	SubredditStats struct {
+		Name             string
+		Subscribers      int
+		ActiveUsers      int
+		NSFW             bool
+		Quarantined      bool
+		Created          time.Time
+		SubscriberGrowth int
+		Points           []SubredditPoint
+	}
+	SubredditPoint struct {
+		Time        time.Time
+		Subscribers int
+		ActiveUsers int
+	}
 	LinkStats struct {
-		Name    string
-		Title   string
-		Author  string
-		UpVotes int
+		Name        string
+		Subreddit   string
+		Title       string
+		Author      string
+		Domain      string
+		Type        PostType
+		Flair       string
+		AuthorFlair string
+		Created     time.Time
+		UpVotes     int
+		Trending    float64
+		Sentiment   float64
+		Removed     string
+		Prediction  *Prediction
+	}
+	MediaStats struct {
+		From             time.Time
+		DomainsByPosts   []MediaCount
+		DomainsByUpVotes []MediaCount
+		Types            []MediaCount
+	}
+	MediaCount struct {
+		Name           string
+		PostCount      int
+		TotalUpVotes   int
+		AverageUpVotes float64
+	}
+	FlairStats struct {
+		Link   []FlairCount
+		Author []FlairCount
+	}
+	FlairCount struct {
+		Flair          string
+		PostCount      int
+		TotalUpVotes   int
+		AverageUpVotes float64
+		Posts          []LinkStats
+	}
+	RemovalStats struct {
+		Posts       int
+		Removed     int
+		Deleted     int
+		RemovalRate float64
+		Reasons     []RemovalReason
+	}
+	RemovalReason struct {
+		Reason string
+		Count  int
+	}
+	Removal struct {
+		Name       string
+		Subreddit  string
+		Title      string
+		Author     string
+		Reason     string
+		UpVotes    int
+		Created    time.Time
+		LastSeen   time.Time
+		DetectedAt time.Time
+	}
+	SentimentStats struct {
+		Average        float64
+		CommentAverage float64
+		Positive       []LinkStats
+		Negative       []LinkStats
+		Points         []SentimentPoint
+	}
+	SentimentPoint struct {
+		Time           time.Time
+		Posts          int
+		Average        float64
+		Comments       int
+		CommentAverage float64
+	}
+	Prediction struct {
+		Score      float64
+		Lower      float64
+		Upper      float64
+		Confidence float64
+	}
+	Backtest struct {
+		Subreddit                  string
+		Samples                    int
+		Folds                      int
+		MeanAbsoluteError          float64
+		MedianAbsolutePercentError float64
+		IntervalCoverage           float64
+		LogRSquared                float64
 	}
 	UserStats struct {
-		Name      string
+		Name        string
+		PostCount   int
+		RepostCount int
+		Suspicion   float64
+		Account     *Account
+	}
+	Repost struct {
+		Name              string
+		Subreddit         string
+		Title             string
+		Author            string
+		Created           time.Time
+		Original          string
+		OriginalSubreddit string
+		OriginalTitle     string
+		Similarity        float64
+		SameURL           bool
+	}
+	Account struct {
+		Created      time.Time
+		LinkKarma    int
+		CommentKarma int
+		Verified     bool
+		Suspended    bool
+		Deleted      bool
+		FetchedAt    time.Time
+	}
+	UserProfile struct {
+		Name       string
+		Account    *Account
+		Subreddits []UserSubredditStats
+	}
+	UserSubredditStats struct {
+		Subreddit string
 		PostCount int
 	}
+	SubredditOverlap struct {
+		Subreddit     string
+		Other         string
+		Authors       int
+		OtherAuthors  int
+		SharedAuthors int
+		Similarity    float64
+	}
+	CrossActiveAuthor struct {
+		Name       string
+		PostCount  int
+		Subreddits []UserSubredditStats
+	}
+	CommentStats struct {
+		Name      string
+		Author    string
+		Body      string
+		Score     int
+		Sentiment float64
+		LinkID    string
+		Created   time.Time
+	}
+	CommenterStats struct {
+		Name         string
+		CommentCount int
+		TotalScore   int
+	}
+	PostHistory struct {
+		Name      string
+		Subreddit string
+		Title     string
+		Points    []ScorePoint
+	}
+	ScorePoint struct {
+		Time        time.Time
+		Ups         int
+		UpvoteRatio float64
+		NumComments int
+	}
+	Rankings struct {
+		Listings    []ListingRankings
+		RisingToHot []RisingToHotStats
+	}
+	ListingRankings struct {
+		Listing string
+		Posts   []RankedLinkStats
+	}
+	RankedLinkStats struct {
+		Name            string
+		Title           string
+		Removed         string
+		BestRank        int
+		LatestRank      int
+		FirstSeen       time.Time
+		LastSeen        time.Time
+		DurationSeconds int64
+		Ranks           []RankPoint
+	}
+	RankPoint struct {
+		Time time.Time
+		Rank int
+	}
+	RisingToHotStats struct {
+		Name     string
+		Title    string
+		RisingAt time.Time
+		HotAt    time.Time
+	}
+	Anomaly struct {
+		Subreddit       string
+		State           VolumeState
+		Since           time.Time
+		IntervalSeconds int64
+		Count           int
+		Expected        float64
+		StdDev          float64
+		ZScore          float64
+		Points          []VolumePoint
+	}
+	VolumePoint struct {
+		Time     time.Time
+		Count    int
+		Expected float64
+		ZScore   float64
+	}
+	Alert struct {
+		Type   AlertType
+		Target string
+		Title  string
+		URL    string
+		Time   time.Time
+	}
+	Health struct {
+		Healthy    bool
+		Ready      bool
+		TokenValid bool
+		Processors []ProcessorStatus
+	}
+	ProcessorStatus struct {
+		Subreddit   string
+		State       ProcessorState
+		StateSince  time.Time
+		LastPoll    time.Time
+		LastError   string
+		Healthy     bool
+		Links       int
+		Users       int
+		MemoryBytes int64
+	}
+	BackfillProgress struct {
+		Subreddit string
+		Listing   string
+		State     BackfillState
+		Pages     int
+		Links     int
+		Oldest    time.Time
+		Until     time.Time
+		LastError string
+	}
+	StatsSort      string
+	StatsGroup     string
+	PostType       string
+	AlertType      string
+	VolumeState    string
+	ProcessorState string
+	BackfillState  string
 )
+
+const (
+	// SortTop sorts posts by upvotes
+	SortTop StatsSort = "top"
+	// SortTrending sorts posts by how quickly they are gaining upvotes
+	SortTrending StatsSort = "trending"
+)
+
+const (
+	// GroupSubreddit groups posts and users by the subreddit they were posted to
+	GroupSubreddit StatsGroup = "subreddit"
+)
+
+const (
+	PostSelf    PostType = "self"
+	PostImage   PostType = "image"
+	PostVideo   PostType = "video"
+	PostGallery PostType = "gallery"
+	PostLink    PostType = "link"
+)
+
+const (
+	// DeletedAuthor replaces the author of posts whose author deleted them or their account
+	DeletedAuthor = "[deleted]"
+	// RemovalDeleted is the reason given for posts that were deleted by their author
+	RemovalDeleted = "deleted"
+	// RemovalRemoved is the reason given for posts that were removed without a category
+	RemovalRemoved = "removed"
+)
+
+const (
+	// AlertNewPost is raised when a followed user publishes a post
+	AlertNewPost AlertType = "new post"
+	// AlertNewMatch is raised when a new post matches a tracked search
+	AlertNewMatch AlertType = "new match"
+	// AlertVolumeSpike is raised when a target gets unusually many new posts
+	AlertVolumeSpike AlertType = "volume spike"
+	// AlertVolumeDrop is raised when a target gets unusually few new posts
+	AlertVolumeDrop AlertType = "volume drop"
+)
+
+const (
+	// VolumeWarmingUp is reported until there are enough intervals to judge the volume against
+	VolumeWarmingUp VolumeState = "warming up"
+	VolumeNormal    VolumeState = "normal"
+	VolumeSpike     VolumeState = "spike"
+	VolumeDrop      VolumeState = "drop"
+)
+
+const (
+	StateInitializing ProcessorState = "initializing"
+	StateRunning      ProcessorState = "running"
+	StateBackingOff   ProcessorState = "backing off"
+	StateFailed       ProcessorState = "failed"
+)
+
+const (
+	BackfillPending  BackfillState = "pending"
+	BackfillRunning  BackfillState = "running"
+	BackfillComplete BackfillState = "complete"
+	BackfillFailed   BackfillState = "failed"
+)
+
+// Created converts the creation timestamp reported by Reddit into a time.Time.
+func (l LinkData) Created() time.Time {
+	return time.Unix(int64(l.CreatedUTC), 0)
+}
+
+// Type classifies the post by its content.
+func (l LinkData) Type() PostType {
+	switch {
+	case l.IsSelf:
+		return PostSelf
+	case l.IsGallery:
+		return PostGallery
+	case l.IsVideo || strings.HasSuffix(l.PostHint, "video"):
+		return PostVideo
+	case l.PostHint == "image":
+		return PostImage
+	default:
+		return PostLink
+	}
+}
+
+// Removal returns why the post is no longer visible: the category reported by Reddit (e.g.
+// "moderator" or "deleted") or a reason based on the placeholders left in its place. Visible
+// posts return an empty string.
+func (l LinkData) Removal() string {
+	switch {
+	case l.RemovedBy == "author":
+		return RemovalDeleted
+	case l.RemovedBy != "":
+		return l.RemovedBy
+	case l.Selftext == "[removed]":
+		return RemovalRemoved
+	case l.Selftext == "[deleted]" || l.Author == DeletedAuthor:
+		return RemovalDeleted
+	default:
+		return ""
+	}
+}
+
+// Created converts the creation timestamp reported by Reddit into a time.Time.
+func (c CommentData) Created() time.Time {
+	return time.Unix(int64(c.CreatedUTC), 0)
+}
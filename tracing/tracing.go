@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/steady-bytes/draft/pkg/chassis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	// Config selects where spans and metrics are exported to. The endpoint is only used by the
+	// otlp exporter and is the host:port of an OTLP/HTTP collector.
+	Config struct {
+		Exporter string
+		Endpoint string
+		Insecure bool
+	}
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Span attributes shared across the application.
+const (
+	SubredditKey   = attribute.Key("reddit.subreddit")
+	CursorKey      = attribute.Key("reddit.cursor")
+	ListingKey     = attribute.Key("reddit.listing")
+	LimitKey       = attribute.Key("reddit.limit")
+	ResultCountKey = attribute.Key("reddit.result_count")
+
+	RateLimitUsedKey      = attribute.Key("reddit.ratelimit.used")
+	RateLimitRemainingKey = attribute.Key("reddit.ratelimit.remaining")
+	RateLimitResetKey     = attribute.Key("reddit.ratelimit.reset")
+)
+
+// Init reads the tracing config and installs the global TracerProvider, MeterProvider and
+// propagator. The returned function flushes any buffered spans and metrics and should be called
+// before the program exits. If tracing is disabled or fails to start, the global no-op providers
+// are left in place.
+func Init(logger chassis.Logger) (shutdown func()) {
+	shutdown = func() {}
+
+	config := Config{}
+	err := chassis.GetConfig().UnmarshalKey("tracing", &config)
+	if err != nil {
+		logger.WithError(err).Error("failed to read tracing config")
+		os.Exit(1)
+	}
+
+	// always propagate incoming trace context even if we aren't exporting our own spans
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, err := newExporter(context.Background(), config)
+	if err != nil {
+		logger.WithError(err).Error("failed to create trace exporter, tracing disabled")
+		return
+	}
+	if exporter == nil {
+		logger.Debug("tracing disabled")
+		return
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(chassis.GetConfig().GetString("service.name")),
+	))
+	if err != nil {
+		logger.WithError(err).Warn("failed to build trace resource, using default")
+		res = resource.Default()
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	logger.WithField("exporter", config.Exporter).Info("tracing enabled")
+
+	// metrics are exported to the same place as the spans
+	var meters *sdkmetric.MeterProvider
+	metricExporter, err := newMetricExporter(context.Background(), config)
+	if err != nil {
+		logger.WithError(err).Error("failed to create metric exporter, metrics disabled")
+	} else {
+		meters = sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+			sdkmetric.WithResource(res),
+		)
+		otel.SetMeterProvider(meters)
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			logger.WithError(err).Error("failed to flush traces")
+		}
+		if meters == nil {
+			return
+		}
+		if err := meters.Shutdown(ctx); err != nil {
+			logger.WithError(err).Error("failed to flush metrics")
+		}
+	}
+}
+
+// End records the error (if any) on the span before ending it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		options := []otlptracehttp.Option{}
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, options...)
+	default:
+		return nil, nil
+	}
+}
+
+func newMetricExporter(ctx context.Context, config Config) (sdkmetric.Exporter, error) {
+	switch config.Exporter {
+	case ExporterStdout:
+		return stdoutmetric.New(stdoutmetric.WithPrettyPrint())
+	case ExporterOTLP:
+		options := []otlpmetrichttp.Option{}
+		if config.Endpoint != "" {
+			options = append(options, otlpmetrichttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlpmetrichttp.WithInsecure())
+		}
+		return otlpmetrichttp.New(ctx, options...)
+	default:
+		return nil, errors.New("unknown exporter")
+	}
+}